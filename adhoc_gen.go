@@ -8,35 +8,83 @@ import (
 	"github.com/AminMal/gopbt/gen"
 )
 
-const complexSize = 50
-
 type simpleAdhocGenerator struct {
 	s                *Session
 	t                reflect.Type
 	structFieldTypes []reflect.Type
+
+	// given are the fields generated elsewhere, e.g. by the generators passed to StructOf, which are left to their zero value
+	given map[string]bool
+}
+
+// generation generates the values of a check case, drawing every random choice from src
+type generation struct {
+	s         *Session
+	src       *gen.Source
+	generated *generatedValues
+
+	// generating counts the values of each type being generated, to detect recursive references
+	generating map[reflect.Type]int
+}
+
+// tree generates a value using g, adhoc generators being given the generation itself so that they share its registries
+func (gn *generation) tree(g anyGen) gen.Tree[reflect.Value] {
+	if sag, ok := g.(*simpleAdhocGenerator); ok {
+		return sag.tree(gn)
+	}
+	return g.GenerateTree(gn.src)
+}
+
+// draw generates a value using g, drawing from src
+func draw[T any](src *gen.Source, g gen.Generator[T]) T {
+	return gen.GenerateTree(g, src).Value
 }
 
 // todo, check if this re-creates adhoc generators everytime
 func (sag *simpleAdhocGenerator) GenerateOne() reflect.Value {
-	v := reflect.New(sag.t).Elem()
+	return sag.GenerateTree(gen.NewSource(rand.Int63())).Value
+}
 
-	for i, ft := range sag.structFieldTypes {
-		if ft.Kind() != reflect.Struct {
-			fieldValue, ok := sag.s.sizedValue(ft, complexSize)
-			if !ok {
-				panic(fmt.Errorf("cannot generate value of type `%s`", ft.Name()))
-			}
-			v.Field(i).Set(fieldValue)
-			continue
+// GenerateTree shrinks structs field by field, using the shrinks of the generators that produced each field
+func (sag *simpleAdhocGenerator) GenerateTree(src *gen.Source) gen.Tree[reflect.Value] {
+	return sag.tree(&generation{s: sag.s, src: src, generated: newGeneratedValues(false)})
+}
+
+func (sag *simpleAdhocGenerator) tree(gn *generation) gen.Tree[reflect.Value] {
+	if sag.t.Kind() != reflect.Struct {
+		tree, ok := gn.reflectiveTree(sag.t, gn.src.Size())
+		if !ok {
+			panic(fmt.Errorf("cannot generate value of type `%s`", sag.t))
 		}
-		g, fieldValue, ok := sag.s.generateSizedGeneratorAndValue(ft, complexSize)
+		return tree
+	}
+	size := gn.levelSize(sag.t, gn.src.Size())
+	defer gn.enter(sag.t)()
+	fields := make([]gen.Tree[reflect.Value], len(sag.structFieldTypes))
+
+	for i := range sag.structFieldTypes {
+		fields[i] = sag.fieldTree(gn, i, size)
+	}
+	return structTree(sag.t, fields)
+}
+
+func (sag *simpleAdhocGenerator) fieldTree(gn *generation, i int, size int) gen.Tree[reflect.Value] {
+	field := sag.t.Field(i)
+	ft := sag.structFieldTypes[i]
+	if sag.given[field.Name] {
+		return gen.Leaf(reflect.Zero(ft))
+	}
+	if sag.s.fieldOverridden(sag.t, field) || ft.Kind() != reflect.Struct || sag.s.hasGeneratorFor(ft) {
+		fieldTree, ok := gn.fieldTree(sag.t, i, size)
 		if !ok {
-			panic(fmt.Errorf("cannot generate value of type `%s`", ft.Name()))
+			panic(prependPath(fmt.Errorf("cannot generate value of type `%s`", ft), "."+field.Name))
 		}
-		sag.s.mapping.setGenerator(ft.Name(), g)
-		v.Field(i).Set(fieldValue)
+		return fieldTree
 	}
-	return v
+	defer annotateGenerationPanic("." + field.Name)
+	g, fieldTree := gn.generateSizedGeneratorAndTree(ft, size)
+	sag.s.mapping.setGenerator(ft, g)
+	return fieldTree
 }
 
 func (sag *simpleAdhocGenerator) GenerateN(n uint) []reflect.Value {
@@ -48,7 +96,8 @@ func (sag *simpleAdhocGenerator) GenerateN(n uint) []reflect.Value {
 }
 
 func (s *Session) adhocValueGenerator(t reflect.Type, size int) (anyGen, bool) {
-	if _, ok := s.sizedValue(t, size); !ok {
+	trial := &generation{s: s, src: gen.NewSource(0), generated: newGeneratedValues(false)}
+	if _, ok := trial.sizedTree(t, size); !ok {
 		return nil, false // if we cannot instantiate now, we cannot also create generators
 	} else {
 		// we're sure that we can create instances now, we can safely ignore the `ok` in adhocGenerator.Generate functions
@@ -57,119 +106,157 @@ func (s *Session) adhocValueGenerator(t reflect.Type, size int) (anyGen, bool) {
 			for i := 0; i < t.NumField(); i++ {
 				fieldTypes[i] = t.Field(i).Type
 			}
-			return &simpleAdhocGenerator{s, t, fieldTypes}, true
+			return &simpleAdhocGenerator{s, t, fieldTypes, nil}, true
 		} else {
-			return &simpleAdhocGenerator{s, t, nil}, true
+			return &simpleAdhocGenerator{s, t, nil, nil}, true
 		}
 	}
 }
 
-func (s *Session) generateSizedGeneratorAndValue(t reflect.Type, size int) (gen anyGen, value reflect.Value, ok bool) {
-	if _, ok2 := s.sizedValue(t, size); !ok2 {
-		return
-	} else {
-		fieldTypes := make([]reflect.Type, t.NumField(), t.NumField())
-		for i := 0; i < t.NumField(); i++ {
-			fieldTypes[i] = t.Field(i).Type
-		}
-		gen = &simpleAdhocGenerator{s, t, fieldTypes}
-		value = gen.GenerateOne()
-		ok = true
-		return
+// generateSizedGeneratorAndTree creates a generator for the struct type t, along with a first tree generated by it
+func (gn *generation) generateSizedGeneratorAndTree(t reflect.Type, size int) (gen anyGen, tree gen.Tree[reflect.Value]) {
+	fieldTypes := make([]reflect.Type, t.NumField(), t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		fieldTypes[i] = t.Field(i).Type
 	}
+	gen = &simpleAdhocGenerator{gn.s, t, fieldTypes, nil}
+	tree = gn.tree(gen)
+	return
 }
 
-// sizedValue is almost the same as sizedValue in testing/quick
+// sizedTree is almost the same as sizedValue in testing/quick, but also provides the shrinks of the generated value.
+// Values nested in the generated one are generated by the generators of the session for their own type, and shrunk with them.
+func (gn *generation) sizedTree(t reflect.Type, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	if g, alreadySupports := gn.s.getGeneratorFor(t); alreadySupports {
+		return gn.tree(g), true
+	}
+	return gn.reflectiveTree(t, size)
+}
 
-func (s *Session) sizedValue(t reflect.Type, size int) (value reflect.Value, ok bool) {
-	v := reflect.New(t).Elem()
+// nestedTree is sizedTree for values nested in a value being generated, segment locates the nested value in generation errors
+func (gn *generation) nestedTree(t reflect.Type, size int, segment string) (tree gen.Tree[reflect.Value], ok bool) {
+	defer annotateGenerationPanic(segment)
+	return gn.sizedTree(t, size)
+}
+
+// fieldTree generates the i-th field of the struct type t, honoring its `gopbt` tag and preferring the generators registered for the field
+func (gn *generation) fieldTree(t reflect.Type, i int, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	field := t.Field(i)
+	defer annotateGenerationPanic("." + field.Name)
+
+	tag := parseTag(field.Tag)
+	if tag.err != nil {
+		panic(tag.err)
+	}
+	if tag.skip {
+		return gen.Leaf(reflect.Zero(field.Type)), true
+	}
+	if g, found := gn.s.mapping.namedGeneratorFor(t, field); found {
+		tree = gn.tree(g)
+		checkAssignable(tree.Value, field.Type)
+		return tree, true
+	}
+	if tag.constrained() {
+		return gn.constrainedTree(field.Type, tag, size), true
+	}
+	return gn.sizedTree(field.Type, size)
+}
 
-	if g, alreadySupports := s.getGeneratorFor(t); alreadySupports {
-		return g.GenerateOne(), true
+// fieldOverridden reports whether the field of the struct type t is generated according to its tag or to a generator registered for it, rather than to its type
+func (s *Session) fieldOverridden(t reflect.Type, field reflect.StructField) bool {
+	if _, named := s.mapping.namedGeneratorFor(t, field); named {
+		return true
+	}
+	tag := parseTag(field.Tag)
+	return tag.err != nil || tag.skip || tag.constrained()
+}
+
+// checkAssignable panics if a value generated by a field or tag generator cannot be used for a field of type t
+func checkAssignable(v reflect.Value, t reflect.Type) {
+	if !v.Type().AssignableTo(t) {
+		panic(fmt.Errorf("generated value of type %s cannot be assigned to %s", v.Type(), t))
+	}
+}
+
+// reflectiveTree generates a value of type t based on its kind, without looking up the generators of the session for t itself.
+// Containers are shrunk by combining the trees of their elements.
+func (gn *generation) reflectiveTree(t reflect.Type, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	switch t.Kind() {
+	case reflect.Map, reflect.Pointer, reflect.Slice:
+		// named containers may reference themselves, such as `type L []L`, and are bounded like recursive structs
+		if t.Name() != "" {
+			size = gn.levelSize(t, size)
+			defer gn.enter(t)()
+		}
 	}
 
 	switch concrete := t; concrete.Kind() {
-	case reflect.Bool:
-		v.SetBool(rand.Int()&1 == 0)
-	case reflect.Float32:
-		v.SetFloat(float64(gen.ArbitraryFloat32.GenerateOne()))
-	case reflect.Float64:
-		v.SetFloat(gen.ArbitraryFloat64.GenerateOne())
-	case reflect.Complex64:
-		v.SetComplex(
-			complex(float64(gen.ArbitraryFloat32.GenerateOne()), float64(gen.ArbitraryFloat32.GenerateOne())),
-		)
-	case reflect.Complex128:
-		v.SetComplex(
-			complex(gen.ArbitraryFloat64.GenerateOne(), gen.ArbitraryFloat64.GenerateOne()),
-		)
-	case reflect.Int16:
-		v.SetInt(int64(gen.ArbitraryInt64.GenerateOne()))
-	case reflect.Int32:
-		v.SetInt(int64(gen.ArbitraryInt64.GenerateOne()))
-	case reflect.Int64:
-		v.SetInt(int64(gen.ArbitraryInt64.GenerateOne()))
-	case reflect.Int8:
-		v.SetInt(int64(gen.ArbitraryInt64.GenerateOne()))
-	case reflect.Int:
-		v.SetInt(int64(gen.ArbitraryInt64.GenerateOne()))
-	case reflect.Uint16:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
-	case reflect.Uint32:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
-	case reflect.Uint64:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
-	case reflect.Uint8:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
-	case reflect.Uint:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
-	case reflect.Uintptr:
-		v.SetUint(gen.ArbitraryUint64.GenerateOne())
 	case reflect.Map:
-		numElems := gen.Between(0, size).GenerateOne()
-		v.Set(reflect.MakeMap(concrete))
+		numElems := 0
+		if !gn.isLeaf(concrete) {
+			numElems = draw(gn.src, gen.Between(0, size))
+		}
+		sizeLeft := size - numElems
+		keys := make([]gen.Tree[reflect.Value], numElems)
+		values := make([]gen.Tree[reflect.Value], numElems)
 		for i := 0; i < numElems; i++ {
-			key, ok1 := s.sizedValue(concrete.Key(), size)
-			value, ok2 := s.sizedValue(concrete.Elem(), size)
+			key, ok1 := gn.nestedTree(concrete.Key(), sizeLeft, "[key]")
+			value, ok2 := gn.nestedTree(concrete.Elem(), sizeLeft, "[value]")
 			if !ok1 || !ok2 {
-				return reflect.Value{}, false
+				return tree, false
 			}
-			v.SetMapIndex(key, value)
+			keys[i], values[i] = key, value
 		}
+		return mapTree(concrete, keys, values), true
 	case reflect.Pointer:
-		if gen.Between(0, size).GenerateOne() == 0 {
-			v.Set(reflect.Zero(concrete)) // Generate nil pointer.
-		} else {
-			elem, ok := s.sizedValue(concrete.Elem(), size)
-			if !ok {
-				return reflect.Value{}, false
-			}
-			v.Set(reflect.New(concrete.Elem()))
-			v.Elem().Set(elem)
+		if gn.isLeaf(concrete) || draw(gn.src, gen.Between(0, size)) == 0 {
+			return gen.Leaf(reflect.Zero(concrete)), true // Generate nil pointer.
 		}
+		elem, ok := gn.nestedTree(concrete.Elem(), size, "*")
+		if !ok {
+			return tree, false
+		}
+		return pointerTree(concrete, elem), true
 	case reflect.Slice:
-		numElems := gen.Between(0, size).GenerateOne()
+		numElems := 0
+		if !gn.isLeaf(concrete) {
+			numElems = draw(gn.src, gen.Between(0, size))
+		}
 		sizeLeft := size - numElems
-		v.Set(reflect.MakeSlice(concrete, numElems, numElems))
+		elems := make([]gen.Tree[reflect.Value], numElems)
 		for i := 0; i < numElems; i++ {
-			elem, ok := s.sizedValue(concrete.Elem(), sizeLeft)
-			if !ok {
-				return reflect.Value{}, false
+			if elems[i], ok = gn.nestedTree(concrete.Elem(), sizeLeft, "[]"); !ok {
+				return tree, false
 			}
-			v.Index(i).Set(elem)
 		}
+		return sequenceTree(elems, 0, func(values []reflect.Value) reflect.Value {
+			v := reflect.MakeSlice(concrete, len(values), len(values))
+			for i, elem := range values {
+				v.Index(i).Set(elem)
+			}
+			return v
+		}), true
 	case reflect.Array:
-		for i := 0; i < v.Len(); i++ {
-			elem, ok := s.sizedValue(concrete.Elem(), size)
-			if !ok {
-				return reflect.Value{}, false
+		elems := make([]gen.Tree[reflect.Value], concrete.Len())
+		for i := range elems {
+			if elems[i], ok = gn.nestedTree(concrete.Elem(), size, "[]"); !ok {
+				return tree, false
 			}
-			v.Index(i).Set(elem)
 		}
+		return sequenceTree(elems, len(elems), func(values []reflect.Value) reflect.Value {
+			v := reflect.New(concrete).Elem()
+			for i, elem := range values {
+				v.Index(i).Set(elem)
+			}
+			return v
+		}), true
 	case reflect.String:
-		v.SetString(defaultStringGen.GenerateOne())
+		return gen.MapTree(gen.GenerateTree(defaultStringGen, gn.src), func(s string) reflect.Value {
+			return reflect.ValueOf(s).Convert(concrete)
+		}), true
 	case reflect.Struct:
-		n := v.NumField()
+		defer gn.enter(concrete)()
+		n := concrete.NumField()
 		// Divide sizeLeft evenly among the struct fields.
 		sizeLeft := size
 		if n > sizeLeft {
@@ -177,16 +264,65 @@ func (s *Session) sizedValue(t reflect.Type, size int) (value reflect.Value, ok
 		} else if n > 0 {
 			sizeLeft /= n
 		}
+		fields := make([]gen.Tree[reflect.Value], n)
 		for i := 0; i < n; i++ {
-			elem, ok := s.sizedValue(concrete.Field(i).Type, sizeLeft)
-			if !ok {
-				return reflect.Value{}, false
+			if fields[i], ok = gn.fieldTree(concrete, i, sizeLeft); !ok {
+				return tree, false
 			}
-			v.Field(i).Set(elem)
 		}
+		return structTree(concrete, fields), true
 	case reflect.Func:
-		// todo: add function implementation support!
-		panic("todo: add function implementation support!")
+		return gn.funcTree(concrete, size)
+	case reflect.Chan:
+		return gn.chanTree(concrete, size)
+	case reflect.Interface:
+		return gn.interfaceTree(concrete, size)
+	default:
+		value, ok := gn.primitiveValue(concrete)
+		if !ok {
+			return tree, false
+		}
+		return gen.Unfold(value, shrinkValue), true
+	}
+}
+
+// primitiveValue generates a value of the boolean or numeric type t
+func (gn *generation) primitiveValue(t reflect.Type) (value reflect.Value, ok bool) {
+	v := reflect.New(t).Elem()
+
+	switch concrete := t; concrete.Kind() {
+	case reflect.Bool:
+		v.SetBool(draw(gn.src, gen.ArbitraryBool))
+	case reflect.Float32:
+		v.SetFloat(float64(draw(gn.src, gen.ArbitraryFloat32)))
+	case reflect.Float64:
+		v.SetFloat(draw(gn.src, gen.ArbitraryFloat64))
+	case reflect.Complex64:
+		v.SetComplex(complex128(draw(gn.src, gen.ArbitraryComplex64)))
+	case reflect.Complex128:
+		v.SetComplex(draw(gn.src, gen.ArbitraryComplex128))
+	case reflect.Int8:
+		v.SetInt(int64(draw(gn.src, gen.ArbitraryInt8)))
+	case reflect.Int16:
+		v.SetInt(int64(draw(gn.src, gen.ArbitraryInt16)))
+	case reflect.Int32:
+		v.SetInt(int64(draw(gn.src, gen.ArbitraryInt32)))
+	case reflect.Int64:
+		v.SetInt(draw(gn.src, gen.ArbitraryInt64))
+	case reflect.Int:
+		v.SetInt(int64(draw(gn.src, gen.ArbitraryInt)))
+	case reflect.Uint8:
+		v.SetUint(uint64(draw(gn.src, gen.ArbitraryUint8)))
+	case reflect.Uint16:
+		v.SetUint(uint64(draw(gn.src, gen.ArbitraryUint16)))
+	case reflect.Uint32:
+		v.SetUint(uint64(draw(gn.src, gen.ArbitraryUint32)))
+	case reflect.Uint64:
+		v.SetUint(draw(gn.src, gen.ArbitraryUint64))
+	case reflect.Uint:
+		v.SetUint(uint64(draw(gn.src, gen.ArbitraryUint)))
+	case reflect.Uintptr:
+		v.SetUint(uint64(draw(gn.src, gen.ArbitraryUintptr)))
 	default:
 		return reflect.Value{}, false
 	}
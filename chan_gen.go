@@ -0,0 +1,93 @@
+package gopbt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// generatedChan describes a generated channel, which is buffered and filled with elems.
+// Channels are consumed by the properties receiving from them, so copying a generated channel creates a new one with the same description.
+type generatedChan struct {
+	t        reflect.Type
+	elems    []reflect.Value
+	capacity int
+	closed   bool
+	owner    *generatedValues
+}
+
+// publishedChan is a channel generated for a check case, along with its description
+type publishedChan struct {
+	// c keeps the channel alive while it is published, so that its address cannot be reused by another channel
+	c  reflect.Value
+	gc *generatedChan
+}
+
+var (
+	publishedChansMu sync.Mutex
+	// publishedChans maps the channels generated for check cases to their description, for deepCopy to copy them.
+	// Check cases remove their channels once done.
+	publishedChans = make(map[uintptr]publishedChan)
+)
+
+func lookupChan(c reflect.Value) (*generatedChan, bool) {
+	if c.IsNil() {
+		return nil, false
+	}
+	publishedChansMu.Lock()
+	defer publishedChansMu.Unlock()
+	published, ok := publishedChans[c.Pointer()]
+	return published.gc, ok
+}
+
+// chanTree generates a channel of type t, filled with up to size elements and closed unless the session keeps channels open.
+// Channels have room for one more element than they are filled with, send-only channels being left empty and open.
+func (gn *generation) chanTree(t reflect.Type, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	if t.ChanDir() == reflect.SendDir {
+		return gen.MapTree(gen.GenerateTree(gen.Between(0, size), gn.src), func(n int) reflect.Value {
+			return (&generatedChan{t: t, capacity: n + 1, owner: gn.generated}).makeChan()
+		}), true
+	}
+	n := draw(gn.src, gen.Between(0, size))
+	elems := make([]gen.Tree[reflect.Value], n)
+	for i := range elems {
+		if elems[i], ok = gn.nestedTree(t.Elem(), size-n, "<-"); !ok {
+			return tree, false
+		}
+	}
+	closed := !gn.s.KeepChannelsOpen
+	return sequenceTree(elems, 0, func(values []reflect.Value) reflect.Value {
+		return (&generatedChan{t, values, len(values) + 1, closed, gn.generated}).makeChan()
+	}), true
+}
+
+func (gc *generatedChan) makeChan() reflect.Value {
+	c := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, gc.t.Elem()), gc.capacity)
+	for _, elem := range gc.elems {
+		c.Send(deepCopy(elem))
+	}
+	if gc.closed {
+		c.Close()
+	}
+	gc.owner.registerChan(c, gc)
+	return c.Convert(gc.t)
+}
+
+// describe prints the channel with the elements it was filled with, e.g. chan int{1, 2} (closed)
+func (gc *generatedChan) describe() string {
+	if gc.t.ChanDir() == reflect.SendDir {
+		return fmt.Sprintf("%s (capacity %d)", gc.t, gc.capacity)
+	}
+	elems := make([]string, len(gc.elems))
+	for i, elem := range gc.elems {
+		elems[i] = gc.owner.describe(elem)
+	}
+	state := "open"
+	if gc.closed {
+		state = "closed"
+	}
+	return fmt.Sprintf("%s{%s} (%s)", gc.t, strings.Join(elems, ", "), state)
+}
@@ -0,0 +1,68 @@
+package gopbt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckError is returned by Session.Check when a property fails.
+// In holds the minimised counterexample, while Original holds the arguments as they were first generated.
+// Message explains why the property failed for In, when the property reports it.
+// Panic and Stack are set when the property panicked for In.
+// Count is the number of checks run until the failure, not counting the Discarded cases.
+// Seed is the seed of the check, running the check again with the same seed reproduces the failure.
+// ReplayedFrom is set when the failing case was read from the failure database of the session, while SavedTo is set when a new failing case was persisted to it.
+type CheckError struct {
+	Count     int
+	Discarded int
+	Seed      int64
+	In        []any
+	Original  []any
+	Shrinks   int
+	Message   string
+	Panic     any
+	Stack     string
+
+	ReplayedFrom string
+	SavedTo      string
+	SaveErr      error
+
+	// inDescriptions and originalDescriptions describe In and Original, printing generated functions as lookup tables
+	inDescriptions       []string
+	originalDescriptions []string
+}
+
+func (e *CheckError) Error() string {
+	var msg string
+	if e.ReplayedFrom != "" {
+		msg = fmt.Sprintf("failed on input %s replayed from %s", formatInputs(e.In, e.inDescriptions), e.ReplayedFrom)
+	} else {
+		msg = fmt.Sprintf("#%d: failed on input %s", e.Count, formatInputs(e.In, e.inDescriptions))
+	}
+	if e.Message != "" {
+		msg += fmt.Sprintf(": %s", e.Message)
+	}
+	if e.Shrinks > 0 {
+		msg += fmt.Sprintf(" (shrunk in %d steps from %s)", e.Shrinks, formatInputs(e.Original, e.originalDescriptions))
+	}
+	if e.ReplayedFrom == "" {
+		msg += fmt.Sprintf(" [seed: %d, reproduce with -gopbtseed=%d or %s=%d]", e.Seed, e.Seed, seedEnv, e.Seed)
+	}
+	if e.SavedTo != "" && e.SaveErr == nil {
+		msg += fmt.Sprintf(" (saved to %s)", e.SavedTo)
+	} else if e.SaveErr != nil {
+		msg += fmt.Sprintf(" (failed to save to the failure database: %s)", e.SaveErr)
+	}
+	return msg
+}
+
+func formatInputs(in []any, descriptions []string) string {
+	if descriptions != nil {
+		return strings.Join(descriptions, ", ")
+	}
+	parts := make([]string, len(in))
+	for i, v := range in {
+		parts[i] = fmt.Sprintf("%#v", v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,36 @@
+package gopbt
+
+import "fmt"
+
+// defaultMaxDiscardRatio is the default number of cases which can be discarded for each check, before giving up
+const defaultMaxDiscardRatio = 10
+
+// discard is the panic value used by Assume to discard the current case
+type discard struct{}
+
+// Assume discards the current case unless cond holds, e.g. Assume(b != 0) before dividing by b.
+// Discarded cases are neither failures nor checks, new cases are generated instead. Assume must only be called by properties.
+func Assume(cond bool) {
+	if !cond {
+		panic(discard{})
+	}
+}
+
+// GaveUpError is returned by Session.Check when too many cases are discarded by Assume before reaching the number of checks
+type GaveUpError struct {
+	Count     int
+	Discarded int
+	Seed      int64
+}
+
+func (e *GaveUpError) Error() string {
+	return fmt.Sprintf("gave up after %d checks, %d cases were discarded [seed: %d, reproduce with -gopbtseed=%d or %s=%d]",
+		e.Count, e.Discarded, e.Seed, e.Seed, seedEnv, e.Seed)
+}
+
+func (s *Session) maxDiscardRatio() int {
+	if s.MaxDiscardRatio > 0 {
+		return s.MaxDiscardRatio
+	}
+	return defaultMaxDiscardRatio
+}
@@ -0,0 +1,168 @@
+package gopbt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// failureEntryHeader is the first line of every file in a failure database, versioning the format of the file.
+// Version 2 added the size of the failing case, which is required to generate its inputs again.
+const failureEntryHeader = "gopbt failure v2"
+
+// failureEntry is a failing case persisted in a failure database, containing everything needed to generate the exact same inputs again
+type failureEntry struct {
+	seed int64
+	size int
+}
+
+func (e failureEntry) encode() []byte {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, failureEntryHeader)
+	fmt.Fprintf(&b, "seed: %d\n", e.seed)
+	fmt.Fprintf(&b, "size: %d\n", e.size)
+	return b.Bytes()
+}
+
+func decodeFailureEntry(data []byte) (e failureEntry, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || scanner.Text() != failureEntryHeader {
+		return e, fmt.Errorf("missing %q header, entries of other versions must be deleted", failureEntryHeader)
+	}
+	var hasSeed, hasSize bool
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return e, fmt.Errorf("malformed line %q", line)
+		}
+		switch strings.TrimSpace(key) {
+		case "seed":
+			if e.seed, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil {
+				return e, fmt.Errorf("invalid seed: %w", err)
+			}
+			hasSeed = true
+		case "size":
+			if e.size, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return e, fmt.Errorf("invalid size: %w", err)
+			} else if e.size <= 0 {
+				return e, fmt.Errorf("invalid size %d", e.size)
+			}
+			hasSize = true
+		default:
+			return e, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return e, err
+	}
+	if !hasSeed || !hasSize {
+		return e, fmt.Errorf("missing seed or size")
+	}
+	return e, nil
+}
+
+// readFailureDatabase returns the entries persisted in dir along with their paths, sorted by path.
+// A missing directory is an empty database.
+func readFailureDatabase(dir string) (entries []failureEntry, paths []string, err error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		entry, err := decodeFailureEntry(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		entries = append(entries, entry)
+		paths = append(paths, path)
+	}
+	return
+}
+
+// writeFailureEntry persists e in dir, in a file named after the hash of its content, and returns the path of the file
+func writeFailureEntry(dir string, e failureEntry) (string, error) {
+	data := e.encode()
+	path := filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256(data))[:16])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// FailureDatabaseFor returns the directory under testdata where failures of the test with the given name are persisted,
+// e.g. FailureDatabaseFor(t.Name()). The -gopbtdb flag sets another directory than testdata/gopbt.
+func FailureDatabaseFor(name string) string {
+	return filepath.Join(append([]string{*failureDatabaseRoot}, pathSegments(name)...)...)
+}
+
+// pathSegments splits a test name into path segments, replacing the characters which are not safe in file names
+func pathSegments(name string) []string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = unsafePathChars.ReplaceAllString(p, "_")
+		if parts[i] == "." || parts[i] == ".." {
+			parts[i] = "_"
+		}
+	}
+	return parts
+}
+
+// checkName names the property f checked by Check, after the test checking it and the type of f, e.g. `TestSort/func(int) bool`.
+// Names of closures are not used, as adding a closure renumbers the ones following it.
+func checkName(f any) string {
+	return fmt.Sprintf("%s/%T", callingTest(), f)
+}
+
+// thisPackage is the import path of this package, telling its functions from the ones calling into it
+var thisPackage = reflect.TypeOf(Session{}).PkgPath()
+
+// callingTest returns the name of the test calling into this package, without its package nor the closures it is called from.
+// Outside of tests, the function calling into this package is used instead.
+func callingTest() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	caller := ""
+	for {
+		frame, more := frames.Next()
+		_, name, _ := strings.Cut(frame.Function[strings.LastIndex(frame.Function, "/")+1:], ".")
+		name, _, _ = strings.Cut(name, ".")
+		for _, prefix := range []string{"Test", "Fuzz", "Benchmark", "Example"} {
+			if strings.HasPrefix(name, prefix) {
+				return name
+			}
+		}
+		if caller == "" && !strings.HasPrefix(frame.Function, thisPackage+".") {
+			caller = name
+		}
+		if !more {
+			return caller
+		}
+	}
+}
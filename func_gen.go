@@ -0,0 +1,281 @@
+package gopbt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// maxHashDepth bounds how deep hashing follows pointers and nested values, so that cyclic inputs can be hashed
+const maxHashDepth = 32
+
+// generatedFunc implements a generated function, returning random outputs which are memoized by input,
+// so that calling the function twice with equal inputs returns equal outputs.
+type generatedFunc struct {
+	s     *Session
+	t     reflect.Type
+	seed  int64
+	size  int
+	owner *generatedValues
+	f     reflect.Value
+
+	// constant holds the outputs of a constant function, shrinking moves functions towards constant ones
+	constant []reflect.Value
+
+	mu    sync.Mutex
+	calls map[uint64]funcCall
+	// recent holds the inputs of the calls made during evaluation, failing the ones made during the last failing evaluation
+	evaluation uint64
+	recent     []uint64
+	failing    []uint64
+}
+
+type funcCall struct {
+	in, out []reflect.Value
+}
+
+func (gv *generatedValues) registerFunc(gf *generatedFunc) {
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	gv.funcs[funcIdentity(gf.f)] = gf
+}
+
+func (gv *generatedValues) lookupFunc(f reflect.Value) (*generatedFunc, bool) {
+	if f.IsNil() {
+		return nil, false
+	}
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	gf, ok := gv.funcs[funcIdentity(f)]
+	return gf, ok
+}
+
+// funcIdentity returns the pointer held by a func value, which is distinct for every func created by reflect.MakeFunc,
+// unlike reflect.Value.Pointer which returns the same code pointer for all of them
+func funcIdentity(f reflect.Value) unsafe.Pointer {
+	holder := reflect.New(f.Type())
+	holder.Elem().Set(f)
+	return *(*unsafe.Pointer)(holder.UnsafePointer())
+}
+
+// funcTree generates a function of type t, reporting false if the outputs of t cannot be generated
+func (gn *generation) funcTree(t reflect.Type, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	for i := 0; i < t.NumOut(); i++ {
+		if _, ok := gn.nestedTree(t.Out(i), size, fmt.Sprintf("(out %d)", i)); !ok {
+			return tree, false
+		}
+	}
+	gf := &generatedFunc{s: gn.s, t: t, seed: draw(gn.src, gen.ArbitraryInt64), size: size, owner: gn.generated}
+	return gf.makeFunc().tree(), true
+}
+
+func (gf *generatedFunc) makeFunc() *generatedFunc {
+	gf.calls = make(map[uint64]funcCall)
+	gf.f = reflect.MakeFunc(gf.t, gf.call)
+	gf.owner.registerFunc(gf)
+	return gf
+}
+
+// tree shrinks the function to the constant functions returned by shrinks
+func (gf *generatedFunc) tree() gen.Tree[reflect.Value] {
+	return gen.NewTree(gf.f, func() []gen.Tree[reflect.Value] {
+		var res []gen.Tree[reflect.Value]
+		for _, c := range gf.shrinks() {
+			res = append(res, c.tree())
+		}
+		return res
+	})
+}
+
+func (gf *generatedFunc) call(in []reflect.Value) []reflect.Value {
+	evaluation := gf.owner.currentEvaluation()
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+
+	h := hashValues(in)
+	if gf.evaluation != evaluation {
+		gf.evaluation, gf.recent = evaluation, nil
+	}
+	if !containsHash(gf.recent, h) {
+		gf.recent = append(gf.recent, h)
+	}
+	if c, ok := gf.calls[h]; ok {
+		return copyValues(c.out)
+	}
+
+	var out []reflect.Value
+	if gf.constant != nil {
+		out = copyValues(gf.constant)
+	} else {
+		// outputs are generated from their own source, seeded by the function and the hash of the inputs,
+		// so that they do not depend on the order of calls
+		gn := &generation{s: gf.s, src: gen.NewSizedSource(gf.seed^int64(h), gf.size), generated: gf.owner}
+		out = make([]reflect.Value, gf.t.NumOut())
+		for i := range out {
+			out[i] = gn.mustNestedTree(gf.t.Out(i), gf.size, fmt.Sprintf("(out %d)", i)).Value
+		}
+	}
+	gf.calls[h] = funcCall{copyValues(in), copyValues(out)}
+	return out
+}
+
+func containsHash(hashes []uint64, h uint64) bool {
+	for _, e := range hashes {
+		if e == h {
+			return true
+		}
+	}
+	return false
+}
+
+// shrinks returns constant functions, first the one returning zero values, then the ones returning the outputs of the last failing evaluation.
+// Constant functions shrink by shrinking their outputs.
+func (gf *generatedFunc) shrinks() []*generatedFunc {
+	var candidates []*generatedFunc
+	constant := func(out []reflect.Value) {
+		c := &generatedFunc{s: gf.s, t: gf.t, owner: gf.owner, constant: out}
+		candidates = append(candidates, c.makeFunc())
+	}
+
+	if gf.constant != nil {
+		for i, o := range gf.constant {
+			for _, shrunk := range shrinkValue(o) {
+				out := copyValues(gf.constant)
+				out[i] = shrunk
+				constant(out)
+			}
+		}
+		return candidates
+	}
+
+	zeros := make([]reflect.Value, gf.t.NumOut())
+	for i := range zeros {
+		zeros[i] = reflect.Zero(gf.t.Out(i))
+	}
+	constant(zeros)
+
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	seen := make(map[uint64]bool)
+	for _, h := range gf.failing {
+		out := gf.calls[h].out
+		if outHash := hashValues(out); !seen[outHash] {
+			seen[outHash] = true
+			constant(copyValues(out))
+		}
+	}
+	return candidates
+}
+
+// describe prints the function as the lookup table of the calls made by the last failing evaluation, e.g. func(int) string{1 → "a", 2 → "b"}
+func (gf *generatedFunc) describe() string {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+
+	var entries []string
+	if gf.constant != nil {
+		entries = append(entries, "_ → "+gf.owner.describeTuple(gf.constant))
+	} else {
+		for _, h := range gf.failing {
+			c := gf.calls[h]
+			entries = append(entries, gf.owner.describeTuple(c.in)+" → "+gf.owner.describeTuple(c.out))
+		}
+	}
+	return fmt.Sprintf("%s{%s}", gf.t, strings.Join(entries, ", "))
+}
+
+func copyValues(values []reflect.Value) []reflect.Value {
+	res := make([]reflect.Value, len(values))
+	for i, v := range values {
+		res[i] = deepCopy(v)
+	}
+	return res
+}
+
+func hashValues(values []reflect.Value) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		hashValue(h, v, 0)
+	}
+	return h.Sum64()
+}
+
+// hashValue writes a deterministic encoding of v to h, following pointers and sorting map entries
+func hashValue(h hash.Hash64, v reflect.Value, depth int) {
+	var buf [8]byte
+	writeUint := func(u uint64) {
+		binary.LittleEndian.PutUint64(buf[:], u)
+		h.Write(buf[:])
+	}
+	if !v.IsValid() || depth > maxHashDepth {
+		writeUint(0)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		writeUint(math.Float64bits(real(v.Complex())))
+		writeUint(math.Float64bits(imag(v.Complex())))
+	case reflect.String:
+		writeUint(uint64(v.Len()))
+		h.Write([]byte(v.String()))
+	case reflect.Slice, reflect.Array:
+		writeUint(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		writeUint(uint64(v.Len()))
+		entries := make([]uint64, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entry := fnv.New64a()
+			hashValue(entry, iter.Key(), depth+1)
+			hashValue(entry, iter.Value(), depth+1)
+			entries = append(entries, entry.Sum64())
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+		for _, e := range entries {
+			writeUint(e)
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			writeUint(0)
+			return
+		}
+		writeUint(1)
+		h.Write([]byte(v.Elem().Type().String()))
+		hashValue(h, v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i), depth+1)
+		}
+	case reflect.Func:
+		// functions are hashed by identity, which unlike their code pointer tells generated functions apart
+		writeUint(uint64(uintptr(funcIdentity(v))))
+	default:
+		// channels and unsafe pointers are hashed by identity
+		writeUint(uint64(v.Pointer()))
+	}
+}
@@ -5,28 +5,48 @@ import "math"
 // ------ int types ------
 var ArbitraryInt Generator[int] = Between((math.MinInt/2 + 1), (math.MaxInt/2 - 1))
 
+var ArbitraryInt8 Generator[int8] = BetweenInclusive(int8(math.MinInt8), int8(math.MaxInt8))
+
+var ArbitraryInt16 Generator[int16] = BetweenInclusive(int16(math.MinInt16), int16(math.MaxInt16))
+
 var ArbitraryInt32 Generator[int32] = Between(int32(math.MinInt32)/2+1, int32(math.MaxInt32)/2-1)
 
 var ArbitraryInt64 Generator[int64]  = Between(int64(math.MinInt64)/2+1, int64(math.MaxInt64)/2-1)
 
 // ------ uint types ------
-var ArbitraryUint Generator[uint] = Between(uint(0), uint(math.MaxUint))
+var ArbitraryUint Generator[uint] = BetweenInclusive(uint(0), uint(math.MaxUint))
+
+var ArbitraryUint8 Generator[uint8] = BetweenInclusive(uint8(0), uint8(math.MaxUint8))
 
-var ArbitraryUint8 Generator[uint8] = Between(uint8(0), uint8(math.MaxUint8))
+var ArbitraryUint16 Generator[uint16] = BetweenInclusive(uint16(0), uint16(math.MaxUint16))
 
-var ArbitraryUint16 Generator[uint16] = Between(uint16(0), uint16(math.MaxUint16))
+var ArbitraryUint32 Generator[uint32] = BetweenInclusive(uint32(0), uint32(math.MaxUint32))
 
-var ArbitraryUint32 Generator[uint32] = Between(uint32(0), uint32(math.MaxUint32))
+var ArbitraryUint64 Generator[uint64] = BetweenInclusive(uint64(0), uint64(math.MaxUint64))
 
-var ArbitraryUint64 Generator[uint64] = Between(uint64(0), uint64(math.MaxUint64))
+var ArbitraryUintptr Generator[uintptr] = BetweenInclusive(uintptr(0), ^uintptr(0))
+
+// ------ byte ------
+var ArbitraryByte Generator[byte] = ArbitraryUint8
 
 // ------ float types ------
 var ArbitraryFloat32 Generator[float32] = Between(float32(math.MinInt32)/2+1, float32(math.MaxInt32)/2-1)
 
 var ArbitraryFloat64 Generator[float64] = Between(float64(math.MinInt64)/2+1, float64(math.MaxInt64)/2-1)
 
-// ------ rune ------
-var ArbitraryRune Generator[rune] = ArbitraryInt32
+// ------ complex types ------
+var ArbitraryComplex64 Generator[complex64] = ComplexBetween(
+	complex(float32(math.MinInt32)/2+1, float32(math.MinInt32)/2+1),
+	complex(float32(math.MaxInt32)/2-1, float32(math.MaxInt32)/2-1),
+)
+
+var ArbitraryComplex128 Generator[complex128] = ComplexBetween(
+	complex(float64(math.MinInt64)/2+1, float64(math.MinInt64)/2+1),
+	complex(float64(math.MaxInt64)/2-1, float64(math.MaxInt64)/2-1),
+)
+
+// ------ bool ------
+var ArbitraryBool Generator[bool] = OneOf(false, true)
 
 // ------ string ------
 
@@ -41,6 +61,17 @@ func (s *stringGen) GenerateOne() string {
 	return string(rs)
 }
 
+// GenerateTree shrinks strings by removing characters, and by replacing characters with the ones appearing earlier in the alphabet
+func (s *stringGen) GenerateTree(src *Source) Tree[string] {
+	strlen := GenerateTree(Between(s.minLength, s.maxLength), src).Value
+	alphabet := &oneOf[rune]{s.alphabet}
+	runes := make([]Tree[rune], strlen)
+	for i := range runes {
+		runes[i] = alphabet.GenerateTree(src)
+	}
+	return MapTree(listTree(runes, s.minLength), func(rs []rune) string { return string(rs) })
+}
+
 func (s *stringGen) GenerateN(n uint) []string {
 	res := make([]string, n)
 	for i := uint(0); i < n; i++ {
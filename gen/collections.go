@@ -0,0 +1,278 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ------ slices ------
+
+type sliceOf[T any] struct {
+	g                    Generator[T]
+	minLength, maxLength int
+}
+
+func (s *sliceOf[T]) GenerateOne() []T {
+	return s.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks slices by removing elements, never below the minimum length, and then by shrinking each element
+func (s *sliceOf[T]) GenerateTree(src *Source) Tree[[]T] {
+	elems := make([]Tree[T], s.minLength+randInt(src, s.maxLength-s.minLength+1))
+	for i := range elems {
+		elems[i] = GenerateTree(s.g, src)
+	}
+	return listTree(elems, s.minLength)
+}
+
+func (s *sliceOf[T]) GenerateN(n uint) [][]T {
+	res := make([][]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = s.GenerateOne()
+	}
+	return res
+}
+
+// SliceOf generates slices of values of g, with a length between minLength and maxLength inclusive
+func SliceOf[T any](g Generator[T], minLength, maxLength uint) Generator[[]T] {
+	return &sliceOf[T]{g, int(numericMin(minLength, maxLength)), int(numericMax(minLength, maxLength))}
+}
+
+// NonEmptySliceOf generates slices of values of g, with a length between 1 and maxLength inclusive
+func NonEmptySliceOf[T any](g Generator[T], maxLength uint) Generator[[]T] {
+	return SliceOf(g, 1, numericMax(1, maxLength))
+}
+
+// ------ unique slices ------
+
+type uniqueSliceOf[T any] struct {
+	g Generator[T]
+	// tracker returns a function telling whether a value is distinct from the ones it was given before
+	tracker              func() func(T) bool
+	minLength, maxLength int
+}
+
+func (u *uniqueSliceOf[T]) GenerateOne() []T {
+	return u.GenerateTree(shared).Value
+}
+
+// GenerateTree generates values until reaching the length of the slice with distinct values, and only shrinks to slices with distinct values
+func (u *uniqueSliceOf[T]) GenerateTree(src *Source) Tree[[]T] {
+	length := u.minLength + randInt(src, u.maxLength-u.minLength+1)
+	isNew := u.tracker()
+	elems := make([]Tree[T], 0, length)
+	for attempt := 0; len(elems) < length && attempt < length*maxFilterAttempts; attempt++ {
+		if e := GenerateTree(u.g, src); isNew(e.Value) {
+			elems = append(elems, e)
+		}
+	}
+	if len(elems) < u.minLength {
+		panic(fmt.Errorf("only %d distinct values were generated out of the minimum of %d", len(elems), u.minLength))
+	}
+	return FilterTree(listTree(elems, u.minLength), u.distinct)
+}
+
+func (u *uniqueSliceOf[T]) distinct(values []T) bool {
+	isNew := u.tracker()
+	for _, v := range values {
+		if !isNew(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (u *uniqueSliceOf[T]) GenerateN(n uint) [][]T {
+	res := make([][]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = u.GenerateOne()
+	}
+	return res
+}
+
+// UniqueSliceBy generates slices of values of g with distinct keys, with a length between minLength and maxLength inclusive.
+// It panics if g does not generate enough distinct keys to reach minLength.
+func UniqueSliceBy[T any, K comparable](g Generator[T], key func(T) K, minLength, maxLength uint) Generator[[]T] {
+	tracker := func() func(T) bool {
+		seen := make(map[K]bool)
+		return func(v T) bool {
+			k := key(v)
+			isNew := !seen[k]
+			seen[k] = true
+			return isNew
+		}
+	}
+	return &uniqueSliceOf[T]{g, tracker, int(numericMin(minLength, maxLength)), int(numericMax(minLength, maxLength))}
+}
+
+// UniqueSliceWith generates slices of values of g which are not equal to each other according to equal, see UniqueSliceBy.
+// Values are compared pairwise, UniqueSliceBy is faster for values which have a comparable key.
+func UniqueSliceWith[T any](g Generator[T], equal func(a, b T) bool, minLength, maxLength uint) Generator[[]T] {
+	tracker := func() func(T) bool {
+		var seen []T
+		return func(v T) bool {
+			for _, s := range seen {
+				if equal(s, v) {
+					return false
+				}
+			}
+			seen = append(seen, v)
+			return true
+		}
+	}
+	return &uniqueSliceOf[T]{g, tracker, int(numericMin(minLength, maxLength)), int(numericMax(minLength, maxLength))}
+}
+
+// UniqueSliceOf generates slices of distinct values of g, see UniqueSliceBy
+func UniqueSliceOf[T comparable](g Generator[T], minLength, maxLength uint) Generator[[]T] {
+	return UniqueSliceBy(g, func(v T) T { return v }, minLength, maxLength)
+}
+
+// ------ arrays ------
+
+type arrayOf[A any, T any] struct {
+	g      Generator[T]
+	length int
+}
+
+func (a *arrayOf[A, T]) GenerateOne() A {
+	return a.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks arrays by shrinking each element, as their length is fixed
+func (a *arrayOf[A, T]) GenerateTree(src *Source) Tree[A] {
+	elems := make([]Tree[T], a.length)
+	for i := range elems {
+		elems[i] = GenerateTree(a.g, src)
+	}
+	return MapTree(listTree(elems, a.length), func(values []T) A {
+		var res A
+		reflect.Copy(reflect.ValueOf(&res).Elem(), reflect.ValueOf(values))
+		return res
+	})
+}
+
+func (a *arrayOf[A, T]) GenerateN(n uint) []A {
+	res := make([]A, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = a.GenerateOne()
+	}
+	return res
+}
+
+// ArrayOf generates arrays of type A filled with values of g, e.g. ArrayOf[[4]byte](ArbitraryByte).
+// As array lengths cannot be type parameters, it panics if A is not an array of T.
+func ArrayOf[A any, T any](g Generator[T]) Generator[A] {
+	t := reflect.TypeOf((*A)(nil)).Elem()
+	if elem := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() != reflect.Array || t.Elem() != elem {
+		panic(fmt.Errorf("ArrayOf: %s is not an array of %s", t, elem))
+	}
+	return &arrayOf[A, T]{g, t.Len()}
+}
+
+// ------ maps ------
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// MapOf generates maps with keys of kg and values of vg, with a length between minLength and maxLength inclusive.
+// Maps shrink by removing entries, and then by shrinking keys and values. It panics if kg does not generate enough distinct keys to reach minLength.
+func MapOf[K comparable, V any](kg Generator[K], vg Generator[V], minLength, maxLength uint) Generator[map[K]V] {
+	entries := lazyGen[entry[K, V]]{
+		genOneFunc: func() entry[K, V] { return entry[K, V]{kg.GenerateOne(), vg.GenerateOne()} },
+		genTreeFunc: func(src *Source) Tree[entry[K, V]] {
+			return zipTree(GenerateTree(kg, src), GenerateTree(vg, src), func(k K, v V) entry[K, V] { return entry[K, V]{k, v} })
+		},
+	}
+	key := func(e entry[K, V]) K { return e.key }
+	return Using(UniqueSliceBy[entry[K, V]](entries, key, minLength, maxLength), func(entries []entry[K, V]) map[K]V {
+		m := make(map[K]V, len(entries))
+		for _, e := range entries {
+			m[e.key] = e.value
+		}
+		return m
+	})
+}
+
+// SetOf generates sets of values of g, as maps to empty structs, with a length between minLength and maxLength inclusive
+func SetOf[T comparable](g Generator[T], minLength, maxLength uint) Generator[map[T]struct{}] {
+	return MapOf(g, Only(struct{}{}), minLength, maxLength)
+}
+
+// ------ permutations and subsets ------
+
+type shuffle[T any] struct {
+	values []T
+}
+
+func (s *shuffle[T]) GenerateOne() []T {
+	return s.GenerateTree(shared).Value
+}
+
+// GenerateTree generates the swaps of a Fisher-Yates shuffle, each of them shrinking towards not swapping at all,
+// so that permutations shrink towards the order of the values
+func (s *shuffle[T]) GenerateTree(src *Source) Tree[[]T] {
+	n := len(s.values)
+	swaps := make([]Tree[int], n)
+	for i := range swaps {
+		i := i
+		swaps[i] = Unfold(i+randInt(src, n-i), func(j int) []int { return towards(i, j) })
+	}
+	return MapTree(listTree(swaps, n), s.permute)
+}
+
+func (s *shuffle[T]) permute(swaps []int) []T {
+	res := make([]T, len(s.values))
+	copy(res, s.values)
+	for i, j := range swaps {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+func (s *shuffle[T]) GenerateN(n uint) [][]T {
+	res := make([][]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = s.GenerateOne()
+	}
+	return res
+}
+
+// Shuffle generates permutations of values, which shrink towards the order of values
+func Shuffle[T any](values []T) Generator[[]T] {
+	return &shuffle[T]{values}
+}
+
+type subsetOf[T any] struct {
+	values []T
+}
+
+func (s *subsetOf[T]) GenerateOne() []T {
+	return s.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks subsets by removing values
+func (s *subsetOf[T]) GenerateTree(src *Source) Tree[[]T] {
+	var elems []Tree[T]
+	for _, v := range s.values {
+		if GenerateTree(ArbitraryBool, src).Value {
+			elems = append(elems, Leaf(v))
+		}
+	}
+	return listTree(elems, 0)
+}
+
+func (s *subsetOf[T]) GenerateN(n uint) [][]T {
+	res := make([][]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = s.GenerateOne()
+	}
+	return res
+}
+
+// SubsetOf generates subsets of values, keeping the order of values, each value being part of a subset with probability 1/2
+func SubsetOf[T any](values []T) Generator[[]T] {
+	return &subsetOf[T]{values}
+}
@@ -1,11 +1,14 @@
 package gen
 
 type lazyGen[K any] struct {
-	genOneFunc func() K
+	genOneFunc  func() K
+	genTreeFunc func(*Source) Tree[K]
 }
 
 func (lg lazyGen[K]) GenerateOne() K { return lg.genOneFunc() }
 
+func (lg lazyGen[K]) GenerateTree(src *Source) Tree[K] { return lg.genTreeFunc(src) }
+
 func (lg lazyGen[K]) GenerateN(n uint) []K {
 	res := make([]K, n)
 	for i := uint(0); i < n; i++ {
@@ -15,7 +18,10 @@ func (lg lazyGen[K]) GenerateN(n uint) []K {
 }
 
 func Using[T any, K any](gen Generator[T], compositionAction func(T) K) Generator[K] {
-	return lazyGen[K]{genOneFunc: func() K { return compositionAction(gen.GenerateOne()) }}
+	return lazyGen[K]{
+		genOneFunc:  func() K { return compositionAction(gen.GenerateOne()) },
+		genTreeFunc: func(src *Source) Tree[K] { return MapTree(GenerateTree(gen, src), compositionAction) },
+	}
 }
 
 type flattenedLazyGen[K any, T any] struct {
@@ -28,6 +34,11 @@ func (f flattenedLazyGen[K, T]) GenerateOne() K {
 	return f.gen(tInstance).GenerateOne()
 }
 
+// GenerateTree shrinks the value used to choose the inner generator first, and then the value of the inner generator itself
+func (f flattenedLazyGen[K, T]) GenerateTree(src *Source) Tree[K] {
+	return bindTree(GenerateTree(f.tGen, src), f.gen, src)
+}
+
 func (f flattenedLazyGen[K, T]) GenerateN(n uint) []K {
 	res := make([]K, n)
 	ts := f.tGen.GenerateN(n)
@@ -40,3 +51,69 @@ func (f flattenedLazyGen[K, T]) GenerateN(n uint) []K {
 func UsingGen[T any, K any](gen Generator[T], flatMapFunc func(T) Generator[K]) Generator[K] {
 	return flattenedLazyGen[K, T]{gen, flatMapFunc}
 }
+
+// Sized creates generators depending on the size of the source they draw from, e.g. Sized(func(size int) Generator[string] { return StringGen(alphabet, 0, uint(size)) }).
+// f is called for every generated value, with the size of its source, see Source.Size.
+func Sized[T any](f func(size int) Generator[T]) Generator[T] {
+	return lazyGen[T]{
+		genOneFunc:  func() T { return f(DefaultSize).GenerateOne() },
+		genTreeFunc: func(src *Source) Tree[T] { return GenerateTree(f(src.Size()), src) },
+	}
+}
+
+// ------ tuples ------
+
+// Tuple2 holds the values generated by Zip2
+type Tuple2[A any, B any] struct {
+	First  A
+	Second B
+}
+
+// Tuple3 holds the values generated by Zip3
+type Tuple3[A any, B any, C any] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// Tuple4 holds the values generated by Zip4
+type Tuple4[A any, B any, C any, D any] struct {
+	First  A
+	Second B
+	Third  C
+	Fourth D
+}
+
+// Map2 combines the values of two independent generators with f, e.g. Map2(nameGen, ageGen, func(name string, age int) Person { return Person{name, age} }).
+// Values shrink generator by generator, in the order the generators are passed.
+func Map2[A any, B any, K any](ga Generator[A], gb Generator[B], f func(A, B) K) Generator[K] {
+	return lazyGen[K]{
+		genOneFunc:  func() K { return f(ga.GenerateOne(), gb.GenerateOne()) },
+		genTreeFunc: func(src *Source) Tree[K] { return zipTree(GenerateTree(ga, src), GenerateTree(gb, src), f) },
+	}
+}
+
+// Map3 is Map2 for three generators
+func Map3[A any, B any, C any, K any](ga Generator[A], gb Generator[B], gc Generator[C], f func(A, B, C) K) Generator[K] {
+	return Map2(Zip2(ga, gb), gc, func(ab Tuple2[A, B], c C) K { return f(ab.First, ab.Second, c) })
+}
+
+// Map4 is Map2 for four generators
+func Map4[A any, B any, C any, D any, K any](ga Generator[A], gb Generator[B], gc Generator[C], gd Generator[D], f func(A, B, C, D) K) Generator[K] {
+	return Map2(Zip3(ga, gb, gc), gd, func(abc Tuple3[A, B, C], d D) K { return f(abc.First, abc.Second, abc.Third, d) })
+}
+
+// Zip2 generates pairs of values of two independent generators
+func Zip2[A any, B any](ga Generator[A], gb Generator[B]) Generator[Tuple2[A, B]] {
+	return Map2(ga, gb, func(a A, b B) Tuple2[A, B] { return Tuple2[A, B]{a, b} })
+}
+
+// Zip3 generates triples of values of three independent generators
+func Zip3[A any, B any, C any](ga Generator[A], gb Generator[B], gc Generator[C]) Generator[Tuple3[A, B, C]] {
+	return Map3(ga, gb, gc, func(a A, b B, c C) Tuple3[A, B, C] { return Tuple3[A, B, C]{a, b, c} })
+}
+
+// Zip4 generates quadruples of values of four independent generators
+func Zip4[A any, B any, C any, D any](ga Generator[A], gb Generator[B], gc Generator[C], gd Generator[D]) Generator[Tuple4[A, B, C, D]] {
+	return Map4(ga, gb, gc, gd, func(a A, b B, c C, d D) Tuple4[A, B, C, D] { return Tuple4[A, B, C, D]{a, b, c, d} })
+}
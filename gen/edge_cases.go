@@ -0,0 +1,190 @@
+package gen
+
+import "math"
+
+// DefaultEdgeProbability is the probability for the Full* generators to generate one of the edge cases of their type
+const DefaultEdgeProbability = 0.1
+
+// ------ edge case biased generators ------
+
+type edgeBiased[T Numeric] struct {
+	g     Generator[T]
+	edges []T
+	p     float64
+}
+
+func (e *edgeBiased[T]) edge(src *Source) (T, bool) {
+	if len(e.edges) == 0 || randFloat64(src) >= e.p {
+		var zero T
+		return zero, false
+	}
+	return e.edges[randInt(src, len(e.edges))], true
+}
+
+func (e *edgeBiased[T]) GenerateOne() T {
+	return e.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks edge cases the same way as the values of the underlying generator
+func (e *edgeBiased[T]) GenerateTree(src *Source) Tree[T] {
+	edge, ok := e.edge(src)
+	if !ok {
+		return GenerateTree(e.g, src)
+	}
+	if shrinker, isShrinker := e.g.(Shrinker[T]); isShrinker {
+		return Unfold(edge, shrinker.Shrink)
+	}
+	return Leaf(edge)
+}
+
+func (e *edgeBiased[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = e.GenerateOne()
+	}
+	return res
+}
+
+// BetweenWithEdges is like Between, but generates the edge cases of [min, max) with probability p:
+// min, the largest value below max, and 0, 1 and -1 when they are in range.
+func BetweenWithEdges[T Numeric](min, max T, p float64) Generator[T] {
+	g := Between(min, max)
+	r, ok := g.(*between[T])
+	if !ok {
+		return g
+	}
+	var zero, one T
+	one++
+	edges := []T{r.min, below(r.max)}
+	for _, v := range []T{zero, one, zero - one} {
+		if r.min <= v && v < r.max {
+			edges = append(edges, v)
+		}
+	}
+	return &edgeBiased[T]{g, distinct(edges), p}
+}
+
+// distinct removes duplicates from values, NaN being a duplicate of NaN
+func distinct[T Numeric](values []T) []T {
+	var res []T
+	for _, v := range values {
+		duplicate := false
+		for _, r := range res {
+			if r == v || (r != r && v != v) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// ------ full range generators ------
+
+// fullRange generates every value of T, including NaN and infinities for floats, which are generated from random bits
+type fullRange[T Numeric] struct{}
+
+func (f fullRange[T]) GenerateOne() T {
+	return f.generate(shared)
+}
+
+func (f fullRange[T]) GenerateTree(src *Source) Tree[T] {
+	return Unfold(f.generate(src), f.Shrink)
+}
+
+func (fullRange[T]) generate(src *Source) T {
+	bits := src.rand.Uint64()
+	var zero T
+	switch any(zero).(type) {
+	case float32:
+		return any(math.Float32frombits(uint32(bits))).(T)
+	case float64:
+		return any(math.Float64frombits(bits)).(T)
+	default:
+		return T(bits)
+	}
+}
+
+func (f fullRange[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = f.GenerateOne()
+	}
+	return res
+}
+
+func (fullRange[T]) Shrink(value T) []T {
+	var zero T
+	return towards(zero, value)
+}
+
+// Full generates values over the whole range of T, generating one of the edge cases of T with probability p:
+// 0, 1, -1, the minimum and maximum values, and for floats -0, the smallest subnormal and normal values, infinities and NaN.
+func Full[T Numeric](p float64) Generator[T] {
+	return &edgeBiased[T]{fullRange[T]{}, fullRangeEdges[T](), p}
+}
+
+func fullRangeEdges[T Numeric]() []T {
+	var zero, one T
+	one++
+	switch any(zero).(type) {
+	case float32:
+		edges := []float32{
+			0, float32(math.Copysign(0, -1)), 1, -1,
+			math.MaxFloat32, -math.MaxFloat32,
+			math.SmallestNonzeroFloat32, -math.SmallestNonzeroFloat32,
+			math.Float32frombits(0x00800000), -math.Float32frombits(0x00800000),
+			float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN()),
+		}
+		return any(edges).([]T)
+	case float64:
+		edges := []float64{
+			0, math.Copysign(0, -1), 1, -1,
+			math.MaxFloat64, -math.MaxFloat64,
+			math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64,
+			math.Float64frombits(0x0010000000000000), -math.Float64frombits(0x0010000000000000),
+			math.Inf(1), math.Inf(-1), math.NaN(),
+		}
+		return any(edges).([]T)
+	default:
+		allOnes := zero - one
+		if allOnes > zero {
+			// unsigned types range from 0 to the value with all bits set
+			return distinct([]T{zero, one, allOnes - one, allOnes})
+		}
+		// the minimum of signed types is the value with only the sign bit set, and their maximum the value with all other bits set
+		min := allOnes
+		for shifted := min; shifted < 0; shifted *= 2 {
+			min = shifted
+		}
+		max := min - one
+		return distinct([]T{zero, one, allOnes, min, min + one, max, max - one})
+	}
+}
+
+var FullInt = Full[int](DefaultEdgeProbability)
+
+var FullInt8 = Full[int8](DefaultEdgeProbability)
+
+var FullInt16 = Full[int16](DefaultEdgeProbability)
+
+var FullInt32 = Full[int32](DefaultEdgeProbability)
+
+var FullInt64 = Full[int64](DefaultEdgeProbability)
+
+var FullUint = Full[uint](DefaultEdgeProbability)
+
+var FullUint8 = Full[uint8](DefaultEdgeProbability)
+
+var FullUint16 = Full[uint16](DefaultEdgeProbability)
+
+var FullUint32 = Full[uint32](DefaultEdgeProbability)
+
+var FullUint64 = Full[uint64](DefaultEdgeProbability)
+
+var FullFloat32 = Full[float32](DefaultEdgeProbability)
+
+var FullFloat64 = Full[float64](DefaultEdgeProbability)
@@ -0,0 +1,45 @@
+package gen
+
+import "fmt"
+
+// maxFilterAttempts bounds the number of values generated by a filtered generator before giving up on satisfying its predicate
+const maxFilterAttempts = 100
+
+type filtered[T any] struct {
+	g    Generator[T]
+	keep func(T) bool
+}
+
+func (f *filtered[T]) GenerateOne() T {
+	return f.GenerateTree(shared).Value
+}
+
+// GenerateTree only shrinks to values satisfying the predicate
+func (f *filtered[T]) GenerateTree(src *Source) Tree[T] {
+	for attempt := 0; attempt < maxFilterAttempts; attempt++ {
+		if tree := GenerateTree(f.g, src); f.keep(tree.Value) {
+			return FilterTree(tree, f.keep)
+		}
+	}
+	panic(fmt.Errorf("no generated value satisfied the filter in %d attempts", maxFilterAttempts))
+}
+
+func (f *filtered[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = f.GenerateOne()
+	}
+	return res
+}
+
+// Filter generates the values of g satisfying keep, generating new values until one does.
+// It panics when no value satisfies keep after 100 attempts, so keep should hold for most values of g;
+// restricting values with Using is preferable otherwise.
+func Filter[T any](g Generator[T], keep func(T) bool) Generator[T] {
+	return &filtered[T]{g, keep}
+}
+
+// SuchThat is Filter
+func SuchThat[T any](g Generator[T], keep func(T) bool) Generator[T] {
+	return Filter(g, keep)
+}
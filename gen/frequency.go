@@ -0,0 +1,89 @@
+package gen
+
+import "fmt"
+
+// WeightedGen is a choice of Frequency, chosen with a probability proportional to its weight
+type WeightedGen[T any] struct {
+	Weight uint
+	Gen    Generator[T]
+}
+
+// WeightedValue is a choice of Weighted, chosen with a probability proportional to its weight
+type WeightedValue[T any] struct {
+	Weight uint
+	Value  T
+}
+
+// ------ weighted selector ------
+
+type frequency[T any] struct {
+	choices []WeightedGen[T]
+	total   uint64
+}
+
+// pick returns the index of a choice, with a probability proportional to its weight
+func (f *frequency[T]) pick(src *Source) int {
+	r := randUint64n(src, f.total)
+	for i, c := range f.choices {
+		if r < uint64(c.Weight) {
+			return i
+		}
+		r -= uint64(c.Weight)
+	}
+	return len(f.choices) - 1
+}
+
+func (f *frequency[T]) generator(i int) Generator[T] { return f.choices[i].Gen }
+
+func (f *frequency[T]) GenerateOne() T {
+	return f.generator(f.pick(shared)).GenerateOne()
+}
+
+// GenerateTree shrinks towards the choices passed first, and then the value of the chosen generator
+func (f *frequency[T]) GenerateTree(src *Source) Tree[T] {
+	index := Unfold(f.pick(src), func(i int) []int { return towards(0, i) })
+	return bindTree(index, f.generator, src)
+}
+
+func (f *frequency[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = f.GenerateOne()
+	}
+	return res
+}
+
+// Frequency chooses one of the generators with a probability proportional to its weight, and generates a value with it,
+// e.g. Frequency(WeightedGen[Request]{90, validRequests}, WeightedGen[Request]{10, malformedRequests}).
+// Choices with a zero weight are never chosen, Frequency panics if all weights are zero.
+func Frequency[T any](choices ...WeightedGen[T]) Generator[T] {
+	f := &frequency[T]{}
+	for _, c := range choices {
+		if c.Weight > 0 {
+			f.choices = append(f.choices, c)
+			f.total += uint64(c.Weight)
+		}
+	}
+	if f.total == 0 {
+		panic(fmt.Errorf("Frequency: no choice with a positive weight"))
+	}
+	return f
+}
+
+// OneOfGen chooses one of the generators uniformly, and generates a value with it
+func OneOfGen[T any](gens ...Generator[T]) Generator[T] {
+	choices := make([]WeightedGen[T], len(gens))
+	for i, g := range gens {
+		choices[i] = WeightedGen[T]{1, g}
+	}
+	return Frequency(choices...)
+}
+
+// Weighted chooses one of the values with a probability proportional to its weight, like OneOf does uniformly
+func Weighted[T any](choices ...WeightedValue[T]) Generator[T] {
+	gens := make([]WeightedGen[T], len(choices))
+	for i, c := range choices {
+		gens[i] = WeightedGen[T]{c.Weight, Only(c.Value)}
+	}
+	return Frequency(gens...)
+}
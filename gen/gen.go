@@ -1,9 +1,6 @@
 package gen
 
-import (
-	"fmt"
-	"math/rand"
-)
+import "math"
 
 type Generator[T any] interface {
 	GenerateOne() T
@@ -34,19 +31,20 @@ type oneOf[T any] struct {
 	values []T
 }
 
-func (o *oneOf[T]) genOne(length int) T {
-	return o.values[rand.Intn(length)]
+func (o *oneOf[T]) GenerateOne() T {
+	return o.GenerateTree(shared).Value
 }
 
-func (o *oneOf[T]) GenerateOne() T {
-	return o.genOne(len(o.values))
+// GenerateTree shrinks towards the values passed first to OneOf
+func (o *oneOf[T]) GenerateTree(src *Source) Tree[T] {
+	index := randInt(src, len(o.values))
+	return MapTree(Unfold(index, func(i int) []int { return towards(0, i) }), func(i int) T { return o.values[i] })
 }
 
 func (o *oneOf[T]) GenerateN(n uint) []T {
 	res := make([]T, n)
-	length := len(o.values)
 	for i := uint(0); i < n; i++ {
-		res[i] = o.genOne(length)
+		res[i] = o.GenerateOne()
 	}
 	return res
 }
@@ -55,9 +53,13 @@ func OneOf[T any](values ...T) Generator[T] {
 	return &oneOf[T]{values: values}
 }
 
-// todo, add support for complex numbers
+// Numeric types are the ordered number types, complex numbers are generated by ComplexBetween
 type Numeric interface {
-	uint8 | uint16 | uint32 | uint64 | uint | int8 | int16 | int32 | int64 | int | float32 | float64
+	uint8 | uint16 | uint32 | uint64 | uint | uintptr | int8 | int16 | int32 | int64 | int | float32 | float64
+}
+
+type Complex interface {
+	complex64 | complex128
 }
 
 func numericMin[T Numeric](a, b T) T {
@@ -81,36 +83,60 @@ type between[T Numeric] struct {
 }
 
 func (r *between[T]) GenerateOne() T {
-	switch diff := any(r.max - r.min).(type) {
-	case uint8:
-		return any(randUint8(diff)).(T) + r.min
-	case uint16:
-		return any(randUint16(diff)).(T) + r.min
-	case uint32:
-		return any(randUint32(diff)).(T) + r.min
-	case uint64:
-		return any(randUint64(diff)).(T) + r.min
-	case uint:
-		return any(randUint(diff)).(T) + r.min
-	case int8:
-		return any(randInt8(diff)).(T) + r.min
-	case int16:
-		return any(randInt16(diff)).(T) + r.min
-	case int32:
-		return any(randInt32(diff)).(T) + r.min
-	case int64:
-		return any(randInt64(diff)).(T) + r.min
-	case int:
-		return any(randInt(diff)).(T) + r.min
+	return r.generate(shared)
+}
+
+func (r *between[T]) GenerateTree(src *Source) Tree[T] {
+	return Unfold(r.generate(src), r.Shrink)
+}
+
+// generate computes the width of the range on 64 bits, as it overflows T for ranges wider than half of its domain
+func (r *between[T]) generate(src *Source) T {
+	switch any(r.min).(type) {
+	case float32, float64:
+		// interpolating rather than adding a fraction of max - min, which overflows for the widest ranges
+		f := randFloat64(src)
+		v := T(float64(r.min)*(1-f) + float64(r.max)*f)
+		if v < r.min || v >= r.max {
+			return r.min
+		}
+		return v
+	case int8, int16, int32, int64, int:
+		return T(int64(r.min) + int64(randUint64n(src, uint64(int64(r.max)-int64(r.min)))))
+	default:
+		return T(uint64(r.min) + randUint64n(src, uint64(r.max)-uint64(r.min)))
+	}
+}
+
+// origin is the value shrinking moves towards, the value of the range nearest to zero
+func (r *between[T]) origin() T {
+	var zero T
+	switch {
+	case r.max <= zero:
+		return below(r.max)
+	case r.min > zero:
+		return r.min
+	default:
+		return zero
+	}
+}
+
+// below returns the largest value of T smaller than v
+func below[T Numeric](v T) T {
+	switch f := any(v).(type) {
 	case float32:
-		return any(randFloat32(diff)).(T) + r.min
+		return any(math.Nextafter32(f, float32(math.Inf(-1)))).(T)
 	case float64:
-		return any(randFloat64(diff)).(T) + r.min
+		return any(math.Nextafter(f, math.Inf(-1))).(T)
 	default:
-		panic(fmt.Errorf("match error: unrecognized Numeric type %t", diff))
+		return v - 1
 	}
 }
 
+func (r *between[T]) Shrink(value T) []T {
+	return towards(r.origin(), value)
+}
+
 func (r *between[T]) GenerateN(n uint) []T {
 	res := make([]T, n)
 	for i := uint(0); i < n; i++ {
@@ -129,3 +155,123 @@ func Between[T Numeric](min, max T) Generator[T] {
 	}
 	return &between[T]{actualMin, actualMax}
 }
+
+// Integer types are the Numeric types without floats
+type Integer interface {
+	uint8 | uint16 | uint32 | uint64 | uint | uintptr | int8 | int16 | int32 | int64 | int
+}
+
+// ------ inclusive range selector ------
+
+type betweenInclusive[T Integer] struct {
+	min, max T
+}
+
+func (r *betweenInclusive[T]) GenerateOne() T {
+	return r.generate(shared)
+}
+
+func (r *betweenInclusive[T]) GenerateTree(src *Source) Tree[T] {
+	return Unfold(r.generate(src), r.Shrink)
+}
+
+// generate computes the width of the range on 64 bits, the whole domain of 64 bits types being drawn from random bits
+func (r *betweenInclusive[T]) generate(src *Source) T {
+	var width uint64
+	switch any(r.min).(type) {
+	case int8, int16, int32, int64, int:
+		width = uint64(int64(r.max) - int64(r.min))
+	default:
+		width = uint64(r.max) - uint64(r.min)
+	}
+	if width == math.MaxUint64 {
+		return T(src.rand.Uint64())
+	}
+	return r.min + T(randUint64n(src, width+1))
+}
+
+// origin is the value shrinking moves towards, the value of the range nearest to zero
+func (r *betweenInclusive[T]) origin() T {
+	var zero T
+	switch {
+	case r.max < zero:
+		return r.max
+	case r.min > zero:
+		return r.min
+	default:
+		return zero
+	}
+}
+
+func (r *betweenInclusive[T]) Shrink(value T) []T {
+	return towards(r.origin(), value)
+}
+
+func (r *betweenInclusive[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = r.GenerateOne()
+	}
+	return res
+}
+
+// BetweenInclusive is like Between, but generates integers in [min, max], so that the maximum of a type can be generated
+func BetweenInclusive[T Integer](min, max T) Generator[T] {
+	actualMin := numericMin(min, max)
+	actualMax := numericMax(min, max)
+
+	if actualMin == actualMax {
+		return Only(min)
+	}
+	return &betweenInclusive[T]{actualMin, actualMax}
+}
+
+// ------ complex range selector ------
+
+type complexBetween[T Complex] struct {
+	real, imag Generator[float64]
+}
+
+func (c *complexBetween[T]) GenerateOne() T {
+	return c.generate(shared)
+}
+
+func (c *complexBetween[T]) GenerateTree(src *Source) Tree[T] {
+	return Unfold(c.generate(src), c.Shrink)
+}
+
+func (c *complexBetween[T]) generate(src *Source) T {
+	return T(complex(GenerateTree(c.real, src).Value, GenerateTree(c.imag, src).Value))
+}
+
+// Shrink shrinks the real part first, and then the imaginary part
+func (c *complexBetween[T]) Shrink(value T) []T {
+	r, i := real(complex128(value)), imag(complex128(value))
+	var res []T
+	if shrinker, ok := c.real.(Shrinker[float64]); ok {
+		for _, sr := range shrinker.Shrink(r) {
+			res = append(res, T(complex(sr, i)))
+		}
+	}
+	if shrinker, ok := c.imag.(Shrinker[float64]); ok {
+		for _, si := range shrinker.Shrink(i) {
+			res = append(res, T(complex(r, si)))
+		}
+	}
+	return res
+}
+
+func (c *complexBetween[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = c.GenerateOne()
+	}
+	return res
+}
+
+// ComplexBetween generates complex numbers in the rectangle between min and max,
+// with a real part in [real(min), real(max)) and an imaginary part in [imag(min), imag(max))
+func ComplexBetween[T Complex](min, max T) Generator[T] {
+	cmin, cmax := complex128(min), complex128(max)
+	return &complexBetween[T]{Between(real(cmin), real(cmax)), Between(imag(cmin), imag(cmax))}
+}
@@ -1,9 +1,15 @@
 package gen
 
 import (
+	"fmt"
+	"math"
+	"regexp"
 	"sort"
+	"strings"
 	"testing"
 	"testing/quick"
+	"unicode"
+	"unicode/utf8"
 )
 
 var globalPropertConf = quick.Config { MaxCount: 1000 }
@@ -190,3 +196,635 @@ func TestGeneratorsBeingEffects(t *testing.T) {
 		"Generator", "generators being effects",
 	)
 }
+
+// ------ Shrinking property tests:
+
+func TestBetweenShrinksStayInRange(t *testing.T) {
+	shrinksStayInRange := func(min, max int16) bool {
+		r := Between(int(min), int(max))
+		tree := GenerateTree(r, shared)
+		for _, s := range tree.Shrinks() {
+			if !isInBetween(r, s.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	checkFailPropery(t, quick.Check(shrinksStayInRange, &globalPropertConf), "Between", "shrinks staying in range")
+}
+
+func TestBetweenShrinksTowardsZero(t *testing.T) {
+	cases := []struct {
+		min, max, expected int
+	}{{-10, 0, -1}, {-10, 10, 0}, {5, 10, 5}}
+
+	for _, c := range cases {
+		tree := GenerateTree(Between(c.min, c.max), shared)
+		for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+			tree = shrinks[0]
+		}
+		if tree.Value != c.expected {
+			t.Errorf("expected Between(%d, %d) to shrink to %d, got %d", c.min, c.max, c.expected, tree.Value)
+		}
+	}
+}
+
+func TestBetweenInclusiveGeneratesItsMaximum(t *testing.T) {
+	for _, v := range BetweenInclusive(int8(-100), int8(100)).GenerateN(1000) {
+		if v < -100 || v > 100 {
+			t.Fatalf("expected values in [-100, 100], got %d", v)
+		}
+	}
+	seen := make(map[uint64]bool)
+	for _, v := range BetweenInclusive(uint64(math.MaxUint64-3), uint64(math.MaxUint64)).GenerateN(1000) {
+		seen[v] = true
+	}
+	if len(seen) != 4 || !seen[math.MaxUint64] {
+		t.Errorf("expected inclusive ranges to generate their maximum, got %v", seen)
+	}
+	// the whole domain of 64 bits types is drawn from random bits
+	BetweenInclusive(int64(math.MinInt64), int64(math.MaxInt64)).GenerateN(1000)
+}
+
+func TestUsingPreservesShrinking(t *testing.T) {
+	personGen := UsingGen(OneOf("John", "Bob"), func(name string) Generator[Person] {
+		return Using(Between(0, 80), func(age int) Person {
+			return Person{Name: name, Age: age}
+		})
+	})
+
+	// repeatedly taking the first shrink should reach the simplest person
+	tree := GenerateTree(personGen, shared)
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		tree = shrinks[0]
+	}
+
+	if tree.Value.Name != "John" || tree.Value.Age != 0 {
+		t.Errorf("expected composed generator to shrink to {John 0}, got %v", tree.Value)
+	}
+}
+
+func TestStringGenShrinksRespectMinLength(t *testing.T) {
+	g := StringGen("abc", 3, 10)
+	for _, s := range GenerateTree(g, shared).Shrinks() {
+		if len(s.Value) < 3 {
+			t.Fatalf("string shrunk below its minimum length: %q", s.Value)
+		}
+	}
+}
+
+func TestSourcesWithTheSameSeedGenerateTheSameValues(t *testing.T) {
+	g := UsingGen(Between(0, 10), func(n int) Generator[string] { return StringGen("abc", uint(n), 20) })
+	generate := func(seed int64) []string {
+		src := NewSource(seed)
+		var res []string
+		for i := 0; i < 10; i++ {
+			res = append(res, GenerateTree(g, src).Value)
+			g.GenerateOne() // draws from the shared source, which must not affect src
+		}
+		return res
+	}
+
+	first, second := generate(42), generate(42)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected sources with the same seed to generate the same values, got %q and %q", first, second)
+		}
+	}
+}
+
+func TestSizedFollowsTheSizeOfItsSource(t *testing.T) {
+	g := Sized(func(size int) Generator[[]int] {
+		return Using(Between(0, size+1), func(n int) []int { return make([]int, n) })
+	})
+
+	for _, size := range []int{0, 3, 20} {
+		for seed := int64(0); seed < 100; seed++ {
+			if tree := GenerateTree(g, NewSizedSource(seed, size)); len(tree.Value) > size {
+				t.Fatalf("expected values of size at most %d, got %d", size, len(tree.Value))
+			}
+		}
+	}
+	for _, v := range g.GenerateN(100) {
+		if len(v) > DefaultSize {
+			t.Fatalf("expected values of size at most %d, got %d", DefaultSize, len(v))
+		}
+	}
+}
+
+func TestBetweenCoversWideRanges(t *testing.T) {
+	wide := []Generator[int64]{Between(int64(math.MinInt64), int64(math.MaxInt64)), Between(int64(-10), int64(math.MaxInt64))}
+	for _, g := range wide {
+		r := g.(*between[int64])
+		for _, v := range g.GenerateN(1000) {
+			if !isInBetween(g, v) {
+				t.Fatalf("expected values in [%d, %d), got %d", r.min, r.max, v)
+			}
+		}
+	}
+	for _, v := range Between(int8(-100), int8(100)).GenerateN(1000) {
+		if v < -100 || v >= 100 {
+			t.Fatalf("expected values in [-100, 100), got %d", v)
+		}
+	}
+	for _, v := range BetweenInclusive(int8(-100), int8(100)).GenerateN(1000) {
+		if v < -100 || v > 100 {
+			t.Fatalf("expected values in [-100, 100], got %d", v)
+		}
+	}
+	seen := make(map[uint64]bool)
+	for _, v := range BetweenInclusive(uint64(math.MaxUint64-3), uint64(math.MaxUint64)).GenerateN(1000) {
+		seen[v] = true
+	}
+	if len(seen) != 4 || !seen[math.MaxUint64] {
+		t.Errorf("expected inclusive ranges to generate their maximum, got %v", seen)
+	}
+	// the whole domain of uint64 is drawn from random bits
+	ArbitraryUint64.GenerateN(1000)
+	if v := Between(-math.MaxFloat64, math.MaxFloat64).GenerateOne(); math.IsInf(v, 0) || math.IsNaN(v) {
+		t.Errorf("expected a finite float, got %v", v)
+	}
+}
+
+func TestBetweenWithEdgesGeneratesEdgeCases(t *testing.T) {
+	seen := make(map[int]bool)
+	for _, v := range BetweenWithEdges(-5, 100, 0.5).GenerateN(1000) {
+		if v < -5 || v >= 100 {
+			t.Fatalf("expected values in [-5, 100), got %d", v)
+		}
+		seen[v] = true
+	}
+	for _, edge := range []int{-5, 99, 0, 1, -1} {
+		if !seen[edge] {
+			t.Errorf("expected edge case %d to be generated", edge)
+		}
+	}
+}
+
+func TestFullGeneratesEdgeCases(t *testing.T) {
+	ints := make(map[int64]bool)
+	for _, v := range Full[int64](0.5).GenerateN(1000) {
+		ints[v] = true
+	}
+	for _, edge := range []int64{math.MinInt64, math.MaxInt64, 0, -1} {
+		if !ints[edge] {
+			t.Errorf("expected edge case %d to be generated", edge)
+		}
+	}
+
+	var nan, negativeZero, inf bool
+	for _, v := range Full[float64](0.5).GenerateN(1000) {
+		nan = nan || math.IsNaN(v)
+		negativeZero = negativeZero || (v == 0 && math.Signbit(v))
+		inf = inf || math.IsInf(v, -1)
+	}
+	if !nan || !negativeZero || !inf {
+		t.Errorf("expected NaN, -0 and -Inf to be generated, got %v, %v and %v", nan, negativeZero, inf)
+	}
+
+	if edges := fullRangeEdges[uint8](); len(edges) != 4 || edges[3] != math.MaxUint8 {
+		t.Errorf("expected the edge cases of uint8 to be 0, 1, 254 and 255, got %v", edges)
+	}
+	if shrinks := Full[float64](1).(*edgeBiased[float64]).g.(Shrinker[float64]).Shrink(math.NaN()); len(shrinks) != 1 || shrinks[0] != 0 {
+		t.Errorf("expected NaN to shrink to 0, got %v", shrinks)
+	}
+}
+
+func TestComplexBetween(t *testing.T) {
+	g := ComplexBetween(complex(-1, 10), complex(1, 20))
+	for _, c := range g.GenerateN(1000) {
+		if real(c) < -1 || real(c) >= 1 || imag(c) < 10 || imag(c) >= 20 {
+			t.Fatalf("expected complex numbers in the rectangle between -1+10i and 1+20i, got %v", c)
+		}
+	}
+
+	tree := GenerateTree(g, NewSource(0))
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		tree = shrinks[0]
+	}
+	if tree.Value != complex(0, 10) {
+		t.Errorf("expected complex numbers to shrink to 0+10i, got %v", tree.Value)
+	}
+}
+
+func TestArbitrarySmallTypesCoverTheirRange(t *testing.T) {
+	var negative, positive bool
+	for _, i := range ArbitraryInt8.GenerateN(1000) {
+		negative = negative || i < -100
+		positive = positive || i > 100
+	}
+	if !negative || !positive {
+		t.Errorf("expected int8 values to cover their whole range")
+	}
+	var maxInt8, maxByte bool
+	for i := 0; i < 5000; i++ {
+		maxInt8 = maxInt8 || ArbitraryInt8.GenerateOne() == math.MaxInt8
+		maxByte = maxByte || ArbitraryByte.GenerateOne() == math.MaxUint8
+	}
+	if !maxInt8 || !maxByte {
+		t.Errorf("expected the maximum of int8 and byte to be generated")
+	}
+
+	bools := make(map[bool]bool)
+	for _, b := range ArbitraryBool.GenerateN(100) {
+		bools[b] = true
+	}
+	if len(bools) != 2 {
+		t.Errorf("expected both booleans to be generated, got %v", bools)
+	}
+}
+
+func TestFrequencyFollowsWeights(t *testing.T) {
+	g := Frequency(WeightedGen[int]{9, Between(0, 10)}, WeightedGen[int]{0, Only(-1)}, WeightedGen[int]{1, Between(100, 110)})
+	small := 0
+	for _, v := range g.GenerateN(10000) {
+		switch {
+		case v >= 0 && v < 10:
+			small++
+		case v < 100 || v >= 110:
+			t.Fatalf("expected values of the weighted generators, got %d", v)
+		}
+	}
+	if small < 8500 || small > 9500 {
+		t.Errorf("expected about 90%% of the values to come from the first generator, got %d out of 10000", small)
+	}
+
+	tree := GenerateTree(g, NewSource(0))
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		tree = shrinks[0]
+	}
+	if tree.Value != 0 {
+		t.Errorf("expected values to shrink towards the first choice, got %d", tree.Value)
+	}
+}
+
+func TestOneOfGenAndWeighted(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, s := range OneOfGen(Only("a"), Only("b"), StringGen("c", 2, 3)).GenerateN(1000) {
+		seen[s] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected values of every generator, got %v", seen)
+	}
+
+	counts := make(map[string]int)
+	for _, s := range Weighted(WeightedValue[string]{3, "valid"}, WeightedValue[string]{1, "malformed"}).GenerateN(4000) {
+		counts[s]++
+	}
+	if counts["valid"] < 2700 || counts["valid"] > 3300 || counts["valid"]+counts["malformed"] != 4000 {
+		t.Errorf("expected about 75%% of valid values, got %v", counts)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Frequency to panic without any positive weight")
+		}
+	}()
+	Weighted(WeightedValue[int]{0, 1})
+}
+
+func TestFilterKeepsSatisfyingValues(t *testing.T) {
+	even := func(i int) bool { return i%2 == 0 }
+	g := Filter(Between(0, 1000), even)
+	for _, v := range g.GenerateN(1000) {
+		if !even(v) {
+			t.Fatalf("expected even values, got %d", v)
+		}
+	}
+	tree := GenerateTree(SuchThat(Between(1, 1000), even), NewSource(0))
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		tree = shrinks[0]
+		if !even(tree.Value) {
+			t.Fatalf("expected shrinks to be even, got %d", tree.Value)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Filter to panic when no value satisfies the predicate")
+		}
+	}()
+	Filter(Between(0, 10), func(i int) bool { return i > 10 }).GenerateOne()
+}
+
+func shrinkFully[T any](tree Tree[T]) T {
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		tree = shrinks[0]
+	}
+	return tree.Value
+}
+
+func TestSliceOfRespectsLengths(t *testing.T) {
+	for _, s := range SliceOf(Between(0, 10), 2, 5).GenerateN(1000) {
+		if len(s) < 2 || len(s) > 5 {
+			t.Fatalf("expected slices of 2 to 5 elements, got %v", s)
+		}
+	}
+	for _, s := range NonEmptySliceOf(Between(0, 10), 3).GenerateN(100) {
+		if len(s) == 0 {
+			t.Fatal("expected non-empty slices")
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(SliceOf(Between(5, 10), 2, 5), NewSource(0))); len(shrunk) != 2 || shrunk[0] != 5 || shrunk[1] != 5 {
+		t.Errorf("expected slices to shrink to [5 5], got %v", shrunk)
+	}
+}
+
+func TestUniqueSliceAndMapOf(t *testing.T) {
+	for _, s := range UniqueSliceOf(Between(0, 20), 5, 10).GenerateN(100) {
+		seen := make(map[int]bool)
+		for _, v := range s {
+			if seen[v] {
+				t.Fatalf("expected distinct values, got %v", s)
+			}
+			seen[v] = true
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(UniqueSliceOf(Between(0, 20), 3, 10), NewSource(0))); len(shrunk) != 3 {
+		t.Errorf("expected unique slices to shrink to 3 distinct values, got %v", shrunk)
+	}
+
+	for _, m := range MapOf(Between(0, 100), StringGen("ab", 0, 3), 3, 6).GenerateN(100) {
+		if len(m) < 3 || len(m) > 6 {
+			t.Fatalf("expected maps of 3 to 6 entries, got %v", m)
+		}
+	}
+	for _, set := range SetOf(Between(0, 100), 1, 4).GenerateN(100) {
+		if len(set) < 1 || len(set) > 4 {
+			t.Fatalf("expected sets of 1 to 4 values, got %v", set)
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(MapOf(Between(0, 100), Between(0, 100), 2, 6), NewSource(0))); len(shrunk) != 2 {
+		t.Errorf("expected maps to shrink to 2 entries, got %v", shrunk)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected UniqueSliceOf to panic without enough distinct values")
+		}
+	}()
+	UniqueSliceOf(Between(0, 3), 5, 5).GenerateOne()
+}
+
+func TestUniqueSliceWith(t *testing.T) {
+	sameParity := func(a, b int) bool { return a%2 == b%2 }
+	for _, s := range UniqueSliceWith(Between(0, 100), sameParity, 2, 2).GenerateN(100) {
+		if sameParity(s[0], s[1]) {
+			t.Fatalf("expected values of distinct parities, got %v", s)
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(UniqueSliceWith(Between(0, 100), sameParity, 1, 5), NewSource(0))); len(shrunk) != 1 {
+		t.Errorf("expected unique slices to shrink to 1 value, got %v", shrunk)
+	}
+	if shrunk := shrinkFully(GenerateTree(UniqueSliceWith(Between(0, 100), sameParity, 2, 2), NewSource(0))); sameParity(shrunk[0], shrunk[1]) {
+		t.Errorf("expected unique slices to only shrink to values of distinct parities, got %v", shrunk)
+	}
+}
+
+func TestArrayOf(t *testing.T) {
+	for _, a := range ArrayOf[[4]int](Between(5, 10)).GenerateN(100) {
+		for _, v := range a {
+			if v < 5 || v >= 10 {
+				t.Fatalf("expected elements between 5 and 10, got %v", a)
+			}
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(ArrayOf[[3]int](Between(5, 10)), NewSource(0))); shrunk != [3]int{5, 5, 5} {
+		t.Errorf("expected arrays to shrink to [5 5 5], got %v", shrunk)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ArrayOf to panic for a type which is not an array of the generated values")
+		}
+	}()
+	ArrayOf[[]int](Between(0, 10))
+}
+
+func TestShuffleAndSubsetOf(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5}
+	for _, p := range Shuffle(values).GenerateN(100) {
+		sorted := append([]int(nil), p...)
+		sort.Ints(sorted)
+		for i := range values {
+			if sorted[i] != values[i] {
+				t.Fatalf("expected a permutation of %v, got %v", values, p)
+			}
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(Shuffle(values), NewSource(0))); fmt.Sprint(shrunk) != fmt.Sprint(values) {
+		t.Errorf("expected permutations to shrink to the original order, got %v", shrunk)
+	}
+
+	for _, s := range SubsetOf(values).GenerateN(100) {
+		for i := 1; i < len(s); i++ {
+			if s[i] <= s[i-1] {
+				t.Fatalf("expected subsets to keep the order of values, got %v", s)
+			}
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(SubsetOf(values), NewSource(0))); len(shrunk) != 0 {
+		t.Errorf("expected subsets to shrink to the empty subset, got %v", shrunk)
+	}
+}
+
+func TestMapAndZipCombineGenerators(t *testing.T) {
+	personGen := Map3(Only("John"), Only("Doe"), Between(20, 30), func(name, surname string, age int) Person {
+		return Person{name, surname, age}
+	})
+	for _, p := range personGen.GenerateN(100) {
+		if p.Name != "John" || p.Surname != "Doe" || p.Age < 20 || p.Age >= 30 {
+			t.Fatalf("unexpected person %v", p)
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(personGen, NewSource(0))); shrunk.Age != 20 {
+		t.Errorf("expected ages to shrink to 20, got %v", shrunk)
+	}
+
+	tree := GenerateTree(Zip4(Between(5, 10), Between(3, 7), OneOf("a", "b"), Between(1, 2)), NewSource(0))
+	if shrunk := shrinkFully(tree); shrunk != (Tuple4[int, int, string, int]{5, 3, "a", 1}) {
+		t.Errorf("expected tuples to shrink each of their values, got %v", shrunk)
+	}
+}
+
+func TestStructOfGeneratesFields(t *testing.T) {
+	personGen := StructOf[Person](map[string]any{"Name": StringGen("ab", 1, 5), "Age": Between(20, 30)})
+	for _, p := range personGen.GenerateN(100) {
+		if len(p.Name) < 1 || len(p.Name) > 5 || p.Surname != "" || p.Age < 20 || p.Age >= 30 {
+			t.Fatalf("unexpected person %v", p)
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(personGen, NewSource(0))); shrunk != (Person{Name: "a", Age: 20}) {
+		t.Errorf("expected people to shrink field by field, got %v", shrunk)
+	}
+
+	for name, fieldGens := range map[string]map[string]any{
+		"unknown field": {"Email": StringGen("ab", 0, 5)},
+		"wrong type":    {"Age": StringGen("ab", 0, 5)},
+		"no generator":  {"Age": 42},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected StructOf to panic for %s", name)
+				}
+			}()
+			StructOf[Person](fieldGens)
+		}()
+	}
+}
+
+func TestStringMatchingStaysWithinTheLanguage(t *testing.T) {
+	for pattern, smallest := range map[string]string{
+		`\+[0-9]{2,3} [0-9]{3}-[0-9]{4}`: "+00 000-0000",
+		`(foo|bar)+-[a-z]*`:              "foo-",
+		`[x-z][a-c]?\d`:                  "x0",
+		`id_[[:upper:]]{2}\.log`:         "id_AA.log",
+	} {
+		matcher := regexp.MustCompile(`^(?:` + pattern + `)$`)
+		g := StringMatching(pattern)
+		for _, s := range g.GenerateN(100) {
+			if !matcher.MatchString(s) {
+				t.Fatalf("expected strings matching %s, got %q", pattern, s)
+			}
+		}
+		tree := GenerateTree(g, NewSource(0))
+		for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+			for _, s := range shrinks {
+				if !matcher.MatchString(s.Value) {
+					t.Fatalf("expected shrinks of %q to match %s, got %q", tree.Value, pattern, s.Value)
+				}
+			}
+			tree = shrinks[0]
+		}
+		if tree.Value != smallest {
+			t.Errorf("expected strings matching %s to shrink to %q, got %q", pattern, smallest, tree.Value)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected StringMatching to panic for invalid patterns")
+		}
+	}()
+	StringMatching(`[a-`)
+}
+
+func TestRuneGeneratorsFollowTheirTables(t *testing.T) {
+	for _, r := range RuneFrom(unicode.Greek, unicode.Han).GenerateN(1000) {
+		if !unicode.In(r, unicode.Greek, unicode.Han) {
+			t.Fatalf("expected Greek or Han runes, got %q", r)
+		}
+	}
+	if shrunk := shrinkFully(GenerateTree(RuneFrom(unicode.Han, unicode.Greek), NewSource(0))); shrunk != rune(unicode.Han.R16[0].Lo) {
+		t.Errorf("expected runes to shrink to the first rune of the first table, got %q", shrunk)
+	}
+
+	nonASCII := 0
+	for _, r := range ArbitraryRune.GenerateN(1000) {
+		if !utf8.ValidRune(r) {
+			t.Fatalf("expected valid runes, got %d", r)
+		}
+		if r > unicode.MaxASCII {
+			nonASCII++
+		}
+	}
+	if nonASCII < 100 || nonASCII > 600 {
+		t.Errorf("expected ArbitraryRune to mix ASCII and other runes, got %d non-ASCII runes out of 1000", nonASCII)
+	}
+	if shrunk := shrinkFully(GenerateTree(StringOf(ArbitraryRune, 2, 10), NewSource(0))); shrunk != "aa" {
+		t.Errorf("expected strings to shrink to \"aa\", got %q", shrunk)
+	}
+}
+
+func TestUTF8Bytes(t *testing.T) {
+	for _, b := range UTF8Bytes(0, 10).GenerateN(1000) {
+		if !utf8.Valid(b) {
+			t.Fatalf("expected valid UTF-8, got %q", b)
+		}
+	}
+	for _, b := range InvalidUTF8Bytes(0, 10).GenerateN(1000) {
+		if utf8.Valid(b) {
+			t.Fatalf("expected invalid UTF-8, got %q", b)
+		}
+	}
+	tree := GenerateTree(InvalidUTF8Bytes(1, 10), NewSource(0))
+	for shrinks := tree.Shrinks(); len(shrinks) > 0; shrinks = tree.Shrinks() {
+		for _, s := range shrinks {
+			if utf8.Valid(s.Value) {
+				t.Fatalf("expected shrinks to stay invalid UTF-8, got %q", s.Value)
+			}
+		}
+		tree = shrinks[0]
+	}
+	if string(tree.Value) != "\xffa" {
+		t.Errorf("expected invalid UTF-8 to shrink to \"\\xffa\", got %q", tree.Value)
+	}
+}
+
+func arithmetic() *Grammar {
+	return NewGrammar(map[string]Rule{
+		"expr":   Alt(Ref("number"), Seq(Ref("expr"), Term(OneOf("+", "*")), Ref("expr")), Seq(Lit("("), Ref("expr"), Lit(")"))),
+		"number": Seq(Term(StringMatching(`[1-9][0-9]{0,3}`)), Opt(Lit(".5"))),
+	})
+}
+
+func nodeDepth(n Node) int {
+	depth := 0
+	for _, c := range n.Children {
+		if d := nodeDepth(c); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+func TestGrammarDerivesTheLanguage(t *testing.T) {
+	expr := regexp.MustCompile(`^[0-9.+*()]+$`)
+	g := arithmetic()
+	g.MaxDepth = 5
+	for _, n := range g.Nodes("expr").GenerateN(500) {
+		if n.Rule != "expr" || !expr.MatchString(n.Text) || strings.Count(n.Text, "(") != strings.Count(n.Text, ")") {
+			t.Fatalf("unexpected derivation %q", n.Text)
+		}
+		if d := nodeDepth(n); d > 5 {
+			t.Fatalf("expected derivations to be at most 5 rules deep, got %d for %q", d, n.Text)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewGrammar to panic for references to missing rules")
+		}
+	}()
+	NewGrammar(map[string]Rule{"a": Ref("b")})
+}
+
+func TestGrammarShrinksSubtrees(t *testing.T) {
+	hasProduct := func(s string) bool { return strings.Contains(s, "*") }
+	g := arithmetic()
+	tree := GenerateTree(g.Strings("expr"), NewSource(0))
+	for seed := int64(1); !hasProduct(tree.Value); seed++ {
+		tree = GenerateTree(g.Strings("expr"), NewSource(seed))
+	}
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for _, s := range tree.Shrinks() {
+			if hasProduct(s.Value) {
+				tree, shrunk = s, true
+				break
+			}
+		}
+	}
+	if tree.Value != "1*1" {
+		t.Errorf("expected expressions with a product to shrink to \"1*1\", got %q", tree.Value)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewGrammar to panic for rules which cannot derive any string")
+		}
+	}()
+	NewGrammar(map[string]Rule{"a": Seq(Lit("x"), Ref("a"))})
+}
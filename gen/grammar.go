@@ -0,0 +1,321 @@
+package gen
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// defaultGrammarDepth is the default nesting of named rules in a derivation, beyond which rules choose the alternatives closest to terminals
+const defaultGrammarDepth = 8
+
+// unreachable is the depth of rules which cannot derive any string
+const unreachable = math.MaxInt32
+
+// Node is a node of the derivation of a string by a Grammar: the rule it derives from, the text it derives,
+// and the nodes of the named rules it is made of, so that generated derivations can be turned into AST values
+type Node struct {
+	Rule     string
+	Text     string
+	Children []Node
+}
+
+// Rule is a rule of a Grammar, built from Lit, Term, Seq, Alt, Repeat, Opt and Ref
+type Rule interface {
+	// derive generates a derivation of the rule drawing from src, depth being the number of named rules it is nested in
+	derive(g *Grammar, depth int, src *Source) Tree[derivation]
+
+	// minDepth returns the smallest nesting of named rules needed to derive the rule, given the ones of the named rules
+	minDepth(depths map[string]int) int
+}
+
+type derivation struct {
+	text     string
+	children []namedDerivation
+}
+
+type namedDerivation struct {
+	rule string
+	tree Tree[derivation]
+}
+
+func (d derivation) node(rule string) Node {
+	children := make([]Node, len(d.children))
+	for i, c := range d.children {
+		children[i] = c.tree.Value.node(c.rule)
+	}
+	return Node{rule, d.text, children}
+}
+
+func concat(parts []derivation) derivation {
+	var b strings.Builder
+	var children []namedDerivation
+	for _, p := range parts {
+		b.WriteString(p.text)
+		children = append(children, p.children...)
+	}
+	return derivation{b.String(), children}
+}
+
+// ------ rules ------
+
+type literal string
+
+func (l literal) derive(*Grammar, int, *Source) Tree[derivation] {
+	return Leaf(derivation{text: string(l)})
+}
+
+func (literal) minDepth(map[string]int) int { return 0 }
+
+// Lit is a rule deriving s
+func Lit(s string) Rule { return literal(s) }
+
+type terminal struct {
+	g Generator[string]
+}
+
+func (t terminal) derive(_ *Grammar, _ int, src *Source) Tree[derivation] {
+	return MapTree(GenerateTree(t.g, src), func(s string) derivation { return derivation{text: s} })
+}
+
+func (terminal) minDepth(map[string]int) int { return 0 }
+
+// Term is a rule deriving the strings of g, e.g. Term(StringMatching(`[a-z]+`)) for identifiers
+func Term(g Generator[string]) Rule { return terminal{g} }
+
+type sequence []Rule
+
+func (s sequence) derive(g *Grammar, depth int, src *Source) Tree[derivation] {
+	parts := make([]Tree[derivation], len(s))
+	for i, r := range s {
+		parts[i] = r.derive(g, depth, src)
+	}
+	return MapTree(listTree(parts, len(parts)), concat)
+}
+
+func (s sequence) minDepth(depths map[string]int) int {
+	res := 0
+	for _, r := range s {
+		if d := r.minDepth(depths); d > res {
+			res = d
+		}
+	}
+	return res
+}
+
+// Seq is a rule deriving the concatenation of the strings derived by rules
+func Seq(rules ...Rule) Rule { return sequence(rules) }
+
+type alternative []Rule
+
+// derive shrinks towards the alternatives passed first, which are expected to be the simplest ones
+func (a alternative) derive(g *Grammar, depth int, src *Source) Tree[derivation] {
+	index := Unfold(a.choose(g, depth, src), func(i int) []int { return towards(0, i) })
+	return bindTree(index, func(i int) Generator[derivation] {
+		return lazyGen[derivation]{
+			genOneFunc:  func() derivation { return a[i].derive(g, depth, shared).Value },
+			genTreeFunc: func(src *Source) Tree[derivation] { return a[i].derive(g, depth, src) },
+		}
+	}, src)
+}
+
+// choose picks one of the alternatives which can be derived within the maximum depth of g,
+// or one of the alternatives closest to terminals if there are none
+func (a alternative) choose(g *Grammar, depth int, src *Source) int {
+	var candidates []int
+	closest := unreachable
+	for i, r := range a {
+		d := r.minDepth(g.minDepths)
+		if d <= g.maxDepth()-depth {
+			candidates = append(candidates, i)
+		}
+		if d < closest {
+			closest = d
+		}
+	}
+	if len(candidates) == 0 {
+		for i, r := range a {
+			if r.minDepth(g.minDepths) == closest {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	return candidates[randInt(src, len(candidates))]
+}
+
+func (a alternative) minDepth(depths map[string]int) int {
+	res := unreachable
+	for _, r := range a {
+		if d := r.minDepth(depths); d < res {
+			res = d
+		}
+	}
+	return res
+}
+
+// Alt is a rule deriving the strings of one of rules, which shrink towards the rules passed first
+func Alt(rules ...Rule) Rule { return alternative(rules) }
+
+type repetition struct {
+	r        Rule
+	min, max int
+}
+
+func (r repetition) derive(g *Grammar, depth int, src *Source) Tree[derivation] {
+	count := r.min
+	if depth < g.maxDepth() {
+		count += randInt(src, r.max-r.min+1)
+	}
+	parts := make([]Tree[derivation], count)
+	for i := range parts {
+		parts[i] = r.r.derive(g, depth, src)
+	}
+	return MapTree(listTree(parts, r.min), concat)
+}
+
+func (r repetition) minDepth(depths map[string]int) int {
+	if r.min == 0 {
+		return 0
+	}
+	return r.r.minDepth(depths)
+}
+
+// Repeat is a rule deriving the concatenation of between min and max inclusive strings derived by r, which shrink by removing repetitions
+func Repeat(r Rule, min, max uint) Rule {
+	return repetition{r, int(numericMin(min, max)), int(numericMax(min, max))}
+}
+
+// Opt is a rule deriving either the empty string or the strings of r
+func Opt(r Rule) Rule { return Repeat(r, 0, 1) }
+
+type reference string
+
+func (ref reference) derive(g *Grammar, depth int, src *Source) Tree[derivation] {
+	return referenceTree(string(ref), g.rules[string(ref)].derive(g, depth+1, src))
+}
+
+// referenceTree shrinks a named rule to the nearest nodes of the same rule it contains, e.g. `(1+2)*3` to `1+2`, and then shrinks its derivation
+func referenceTree(rule string, tree Tree[derivation]) Tree[derivation] {
+	return NewTree(derivation{tree.Value.text, []namedDerivation{{rule, tree}}}, func() []Tree[derivation] {
+		var res []Tree[derivation]
+		for _, nested := range nearest(rule, tree.Value) {
+			res = append(res, referenceTree(rule, nested))
+		}
+		for _, c := range tree.Shrinks() {
+			res = append(res, referenceTree(rule, c))
+		}
+		return res
+	})
+}
+
+// nearest returns the derivations of the nodes of rule in d, which are not nested in another node of rule
+func nearest(rule string, d derivation) []Tree[derivation] {
+	var res []Tree[derivation]
+	for _, c := range d.children {
+		if c.rule == rule {
+			res = append(res, c.tree)
+		} else {
+			res = append(res, nearest(rule, c.tree.Value)...)
+		}
+	}
+	return res
+}
+
+func (ref reference) minDepth(depths map[string]int) int {
+	if d := depths[string(ref)]; d < unreachable {
+		return d + 1
+	}
+	return unreachable
+}
+
+// Ref is a rule deriving the strings of the rule named name in the grammar, which may be recursive
+func Ref(name string) Rule { return reference(name) }
+
+// ------ grammar ------
+
+// Grammar generates the strings derived by named rules, e.g. for arithmetic expressions:
+//
+//	NewGrammar(map[string]Rule{
+//		"expr":   Alt(Ref("number"), Seq(Ref("expr"), Term(OneOf("+", "*")), Ref("expr")), Seq(Lit("("), Ref("expr"), Lit(")"))),
+//		"number": Term(StringMatching(`[1-9][0-9]{0,3}`)),
+//	})
+type Grammar struct {
+	// MaxDepth is the nesting of named rules beyond which derivations choose the alternatives closest to terminals, 8 by default
+	MaxDepth int
+
+	rules     map[string]Rule
+	minDepths map[string]int
+}
+
+// NewGrammar creates a grammar from its named rules.
+// It panics if a rule references a rule missing from rules, or if a rule cannot derive any string, e.g. `a := Seq(Lit("x"), Ref("a"))`.
+func NewGrammar(rules map[string]Rule) *Grammar {
+	g := &Grammar{rules: rules, minDepths: make(map[string]int, len(rules))}
+	for name, r := range rules {
+		checkReferences(name, r, rules)
+		g.minDepths[name] = unreachable
+	}
+	// the depths of rules only decrease, until no rule can be derived with a smaller nesting
+	for changed := true; changed; {
+		changed = false
+		for name, r := range rules {
+			if d := r.minDepth(g.minDepths); d < g.minDepths[name] {
+				g.minDepths[name] = d
+				changed = true
+			}
+		}
+	}
+	for name, d := range g.minDepths {
+		if d == unreachable {
+			panic(fmt.Errorf("NewGrammar: rule %s cannot derive any string", name))
+		}
+	}
+	return g
+}
+
+func checkReferences(name string, r Rule, rules map[string]Rule) {
+	switch r := r.(type) {
+	case reference:
+		if _, ok := rules[string(r)]; !ok {
+			panic(fmt.Errorf("NewGrammar: rule %s references missing rule %s", name, string(r)))
+		}
+	case sequence:
+		for _, sub := range r {
+			checkReferences(name, sub, rules)
+		}
+	case alternative:
+		for _, sub := range r {
+			checkReferences(name, sub, rules)
+		}
+	case repetition:
+		checkReferences(name, r.r, rules)
+	}
+}
+
+func (g *Grammar) maxDepth() int {
+	if g.MaxDepth > 0 {
+		return g.MaxDepth
+	}
+	return defaultGrammarDepth
+}
+
+// Nodes generates derivations of the rule named start, which shrink by replacing nodes with nested nodes of the same rule,
+// by choosing the alternatives passed first, and by removing repetitions. It panics if there is no such rule.
+func (g *Grammar) Nodes(start string) Generator[Node] {
+	if _, ok := g.rules[start]; !ok {
+		panic(fmt.Errorf("Nodes: no rule %s", start))
+	}
+	return lazyGen[Node]{
+		genOneFunc:  func() Node { return g.derive(start, shared).Value },
+		genTreeFunc: func(src *Source) Tree[Node] { return g.derive(start, src) },
+	}
+}
+
+func (g *Grammar) derive(start string, src *Source) Tree[Node] {
+	return MapTree(reference(start).derive(g, 0, src), func(d derivation) Node { return d.children[0].tree.Value.node(start) })
+}
+
+// Strings generates the strings derived by the rule named start, which shrink like the derivations of Nodes
+func (g *Grammar) Strings(start string) Generator[string] {
+	return Using(g.Nodes(start), func(n Node) string { return n.Text })
+}
@@ -1,55 +1,68 @@
 package gen
 
-import "math/rand"
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
 
-func randUint8(n uint8) uint8 {
-	return uint8(rand.Int31n(int32(n)))
+// Source is the randomness generators draw from. Generators pass the source they are given on to the generators they are made of,
+// so that generating from sources created with the same seed yields the same values, whatever is generated elsewhere meanwhile.
+// A Source must not be used by several goroutines at once.
+type Source struct {
+	rand *rand.Rand
+	size int
 }
 
-func randUint16(n uint16) uint16 {
-	return uint16(rand.Int31n(int32(n)))
+// NewSource creates a source of randomness seeded with seed
+func NewSource(seed int64) *Source {
+	return &Source{rand.New(rand.NewSource(seed)), DefaultSize}
 }
 
-func randUint32(n uint32) uint32 {
-	return uint32(rand.Int31n(int32(n)))
-}
-
-func randUint64(n uint64) uint64 {
-	return uint64(rand.Int63n(int64(n)))
-}
-
-func randUint(n uint) uint {
-	if n <= 1<<32-1 {
-		return uint(randUint32(uint32(n)))
-	} else {
-		return uint(randUint64(uint64(n)))
-	}
-}
+// shared is the source of the GenerateOne and GenerateN methods of generators, which may be called from several goroutines at once
+var shared = &Source{rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano()).(rand.Source64)}), DefaultSize}
 
-func randInt8(n int8) int8 {
-	return int8(rand.Int31n(int32(n)))
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
 }
 
-func randInt16(n int16) int16 {
-	return int16(rand.Int31n(int32(n)))
+func (l *lockedSource) Int63() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.src.Int63()
 }
 
-func randInt32(n int32) int32 {
-	return rand.Int31n(n)
+func (l *lockedSource) Uint64() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.src.Uint64()
 }
 
-func randInt64(n int64) int64 {
-	return rand.Int63n(n)
+func (l *lockedSource) Seed(seed int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.src.Seed(seed)
 }
 
-func randInt(n int) int {
-	return rand.Intn(n)
+// randUint64n returns a uniformly distributed value in [0, n), n must not be zero
+func randUint64n(src *Source, n uint64) uint64 {
+	if n <= math.MaxInt64 {
+		return uint64(src.rand.Int63n(int64(n)))
+	}
+	// at least half of the values are below n, so that a few draws are enough
+	for {
+		if v := src.rand.Uint64(); v < n {
+			return v
+		}
+	}
 }
 
-func randFloat32(n float32) float32 {
-	return rand.Float32() * n
+func randInt(src *Source, n int) int {
+	return src.rand.Intn(n)
 }
 
-func randFloat64(n float64) float64 {
-	return rand.Float64() * n
+func randFloat64(src *Source) float64 {
+	return src.rand.Float64()
 }
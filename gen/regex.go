@@ -0,0 +1,161 @@
+package gen
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxRegexRepeat bounds the repetitions of unbounded operators such as `*` and `+` when generating strings from a regular expression
+const maxRegexRepeat = 10
+
+// maxRegexAttempts bounds the number of strings generated from a regular expression before giving up on finding a match,
+// as assertions such as `\b` are not taken into account while generating
+const maxRegexAttempts = 100
+
+type stringMatching struct {
+	re      *syntax.Regexp
+	matcher *regexp.Regexp
+}
+
+func (s *stringMatching) GenerateOne() string {
+	return s.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks strings by repeating less, choosing the alternatives written first, and choosing the smallest characters of classes,
+// so that shrunk strings still match the regular expression
+func (s *stringMatching) GenerateTree(src *Source) Tree[string] {
+	for attempt := 0; attempt < maxRegexAttempts; attempt++ {
+		tree := regexTree(s.re, src)
+		if s.matcher.MatchString(tree.Value) {
+			return FilterTree(tree, s.matcher.MatchString)
+		}
+	}
+	panic(fmt.Errorf("cannot generate a string matching %s", s.re))
+}
+
+func (s *stringMatching) GenerateN(n uint) []string {
+	res := make([]string, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = s.GenerateOne()
+	}
+	return res
+}
+
+// StringMatching generates strings matching the regular expression pattern, in the syntax of the regexp package,
+// e.g. StringMatching(`\+[0-9]{2,3} [0-9]{3}-[0-9]{4}`) for phone numbers.
+// The whole string matches pattern, and unbounded repetitions such as `*` and `+` repeat at most 10 times. It panics if pattern is invalid.
+func StringMatching(pattern string) Generator[string] {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		panic(fmt.Errorf("StringMatching: %w", err))
+	}
+	// the whole string must match, not only a part of it
+	matcher := regexp.MustCompile(`^(?:` + pattern + `)$`)
+	return &stringMatching{re.Simplify(), matcher}
+}
+
+// regexGen generates the strings of a sub-expression, to be chosen among alternatives
+type regexGen struct {
+	re *syntax.Regexp
+}
+
+func (r regexGen) GenerateOne() string { return regexTree(r.re, shared).Value }
+
+func (r regexGen) GenerateTree(src *Source) Tree[string] { return regexTree(r.re, src) }
+
+func (r regexGen) GenerateN(n uint) []string {
+	res := make([]string, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = r.GenerateOne()
+	}
+	return res
+}
+
+func regexTree(re *syntax.Regexp, src *Source) Tree[string] {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		panic(fmt.Errorf("regular expression %s matches nothing", re))
+	case syntax.OpLiteral:
+		return Leaf(string(re.Rune))
+	case syntax.OpCharClass:
+		return MapTree(classTree(re.Rune, src), func(r rune) string { return string(r) })
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return MapTree(GenerateTree(Between(' ', '~'+1), src), func(r rune) string { return string(r) })
+	case syntax.OpCapture:
+		return regexTree(re.Sub[0], src)
+	case syntax.OpConcat:
+		subs := make([]Tree[string], len(re.Sub))
+		for i, sub := range re.Sub {
+			subs[i] = regexTree(sub, src)
+		}
+		return MapTree(listTree(subs, len(subs)), joinStrings)
+	case syntax.OpAlternate:
+		index := Unfold(randInt(src, len(re.Sub)), func(i int) []int { return towards(0, i) })
+		return bindTree(index, func(i int) Generator[string] { return regexGen{re.Sub[i]} }, src)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatBounds(re)
+		reps := make([]Tree[string], min+randInt(src, max-min+1))
+		for i := range reps {
+			reps[i] = regexTree(re.Sub[0], src)
+		}
+		return MapTree(listTree(reps, min), joinStrings)
+	default:
+		// empty matches and assertions do not produce any character
+		return Leaf("")
+	}
+}
+
+func joinStrings(parts []string) string { return strings.Join(parts, "") }
+
+func repeatBounds(re *syntax.Regexp) (min, max int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxRegexRepeat
+	case syntax.OpPlus:
+		return 1, maxRegexRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	default:
+		if re.Max < 0 {
+			return re.Min, re.Min + maxRegexRepeat
+		}
+		return re.Min, re.Max
+	}
+}
+
+// classTree picks a rune in a character class, given as pairs of inclusive bounds, avoiding surrogates which are not valid runes.
+// Runes shrink towards the lower bounds of the ranges written first.
+func classTree(ranges []rune, src *Source) Tree[rune] {
+	for {
+		i := randInt(src, len(ranges)/2) * 2
+		r := ranges[i] + rune(randInt(src, int(ranges[i+1]-ranges[i])+1))
+		if validRune(r) {
+			return Unfold(r, func(r rune) []rune { return classShrinks(ranges, r) })
+		}
+	}
+}
+
+func classShrinks(ranges []rune, r rune) []rune {
+	var res []rune
+	for i := 0; i < len(ranges); i += 2 {
+		if r <= ranges[i+1] {
+			for _, c := range towards(ranges[i], r) {
+				if validRune(c) {
+					res = append(res, c)
+				}
+			}
+			return res
+		}
+		if validRune(ranges[i]) {
+			res = append(res, ranges[i])
+		}
+	}
+	return res
+}
+
+func validRune(r rune) bool {
+	return !(r >= 0xD800 && r <= 0xDFFF) && r <= unicode.MaxRune
+}
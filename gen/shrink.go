@@ -0,0 +1,175 @@
+package gen
+
+// Tree is a generated value together with the simpler values it can be shrunk to.
+// Each shrink is itself a Tree, so a failing value can be minimised step by step.
+// Shrinks are computed lazily, and only when asked for.
+type Tree[T any] struct {
+	Value   T
+	shrinks func() []Tree[T]
+}
+
+func (t Tree[T]) Shrinks() []Tree[T] {
+	if t.shrinks == nil {
+		return nil
+	}
+	return t.shrinks()
+}
+
+// NewTree creates a tree from a value and a function computing its shrinks
+func NewTree[T any](value T, shrinks func() []Tree[T]) Tree[T] {
+	return Tree[T]{Value: value, shrinks: shrinks}
+}
+
+// Leaf creates a tree which cannot be shrunk any further
+func Leaf[T any](value T) Tree[T] { return Tree[T]{Value: value} }
+
+// Unfold creates a tree by repeatedly applying shrink to value and to each of its shrinks
+func Unfold[T any](value T, shrink func(T) []T) Tree[T] {
+	return Tree[T]{
+		Value: value,
+		shrinks: func() []Tree[T] {
+			candidates := shrink(value)
+			res := make([]Tree[T], len(candidates))
+			for i, c := range candidates {
+				res[i] = Unfold(c, shrink)
+			}
+			return res
+		},
+	}
+}
+
+// MapTree applies f to every value in the tree
+func MapTree[T any, K any](t Tree[T], f func(T) K) Tree[K] {
+	return Tree[K]{
+		Value: f(t.Value),
+		shrinks: func() []Tree[K] {
+			children := t.Shrinks()
+			res := make([]Tree[K], len(children))
+			for i, c := range children {
+				res[i] = MapTree(c, f)
+			}
+			return res
+		},
+	}
+}
+
+// Shrinker is implemented by generators that can propose simpler candidates for a value they generated
+type Shrinker[T any] interface {
+	Shrink(T) []T
+}
+
+// TreeGenerator is implemented by generators that generate values together with their shrinks, drawing from the source they are given
+type TreeGenerator[T any] interface {
+	Generator[T]
+	GenerateTree(src *Source) Tree[T]
+}
+
+// GenerateTree generates a value using g, along with its shrinks if g supports shrinking.
+// Generators that do not implement TreeGenerator draw from a source shared by the whole program instead of src.
+func GenerateTree[T any](g Generator[T], src *Source) Tree[T] {
+	switch sg := g.(type) {
+	case TreeGenerator[T]:
+		return sg.GenerateTree(src)
+	case Shrinker[T]:
+		return Unfold(g.GenerateOne(), sg.Shrink)
+	default:
+		return Leaf(g.GenerateOne())
+	}
+}
+
+func bindTree[T any, K any](t Tree[T], f func(T) Generator[K], src *Source) Tree[K] {
+	inner := GenerateTree(f(t.Value), src)
+	return Tree[K]{
+		Value: inner.Value,
+		shrinks: func() []Tree[K] {
+			var res []Tree[K]
+			for _, outer := range t.Shrinks() {
+				res = append(res, bindTree(outer, f, src))
+			}
+			return append(res, inner.Shrinks()...)
+		},
+	}
+}
+
+// FilterTree removes the shrinks of tree which do not satisfy keep, along with their own shrinks
+func FilterTree[T any](tree Tree[T], keep func(T) bool) Tree[T] {
+	return NewTree(tree.Value, func() []Tree[T] {
+		var res []Tree[T]
+		for _, c := range tree.Shrinks() {
+			if keep(c.Value) {
+				res = append(res, FilterTree(c, keep))
+			}
+		}
+		return res
+	})
+}
+
+// zipTree combines two trees with f, shrinking the first value and then the second one
+func zipTree[A any, B any, C any](a Tree[A], b Tree[B], f func(A, B) C) Tree[C] {
+	return NewTree(f(a.Value, b.Value), func() []Tree[C] {
+		var res []Tree[C]
+		for _, sa := range a.Shrinks() {
+			res = append(res, zipTree(sa, b, f))
+		}
+		for _, sb := range b.Shrinks() {
+			res = append(res, zipTree(a, sb, f))
+		}
+		return res
+	})
+}
+
+// listTree combines the trees of elements into a tree of lists, shrinking by removing elements first and then by shrinking each element.
+// Lists are never shrunk below minLength elements.
+func listTree[T any](elems []Tree[T], minLength int) Tree[[]T] {
+	values := make([]T, len(elems))
+	for i, e := range elems {
+		values[i] = e.Value
+	}
+	return Tree[[]T]{
+		Value: values,
+		shrinks: func() []Tree[[]T] {
+			var res []Tree[[]T]
+			n := len(elems)
+			for k := n - minLength; k > 0; k /= 2 {
+				for start := 0; start+k <= n; start += k {
+					kept := make([]Tree[T], 0, n-k)
+					kept = append(kept, elems[:start]...)
+					kept = append(kept, elems[start+k:]...)
+					res = append(res, listTree(kept, minLength))
+				}
+			}
+			for i, e := range elems {
+				for _, c := range e.Shrinks() {
+					replaced := make([]Tree[T], n)
+					copy(replaced, elems)
+					replaced[i] = c
+					res = append(res, listTree(replaced, minLength))
+				}
+			}
+			return res
+		},
+	}
+}
+
+// maxNumericShrinks bounds the candidates of a single numeric value, as halving a float would otherwise take hundreds of steps to reach the origin
+const maxNumericShrinks = 64
+
+// towards returns values between origin and value, starting from origin and getting closer to value
+func towards[T Numeric](origin, value T) []T {
+	if value == origin {
+		return nil
+	}
+	res := []T{origin}
+	if value-value != 0 {
+		// NaN and infinities, for which halving the distance to origin never ends
+		return res
+	}
+	for d := (value - origin) / 2; d != 0 && len(res) < maxNumericShrinks; d /= 2 {
+		c := value - d
+		if c == res[len(res)-1] || c == value {
+			break
+		}
+		res = append(res, c)
+	}
+	return res
+}
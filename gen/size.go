@@ -0,0 +1,20 @@
+package gen
+
+// DefaultSize is the size passed to Sized generators by sources created with NewSource, and by GenerateOne and GenerateN
+const DefaultSize = 50
+
+// NewSizedSource creates a source of randomness seeded with seed, passing size to the Sized generators drawing from it.
+// Sessions create one for every check of a property, growing the size from small to large sizes across checks.
+func NewSizedSource(seed int64, size int) *Source {
+	if size < 0 {
+		size = 0
+	}
+	src := NewSource(seed)
+	src.size = size
+	return src
+}
+
+// Size returns the size passed to Sized generators drawing from src, which bounds the size of the values they generate, such as the length of collections
+func (src *Source) Size() int {
+	return src.size
+}
@@ -0,0 +1,134 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type structField struct {
+	index int
+	gen   reflect.Value
+}
+
+type structOf[T any] struct {
+	t      reflect.Type
+	fields []structField
+}
+
+func (s *structOf[T]) GenerateOne() T {
+	return s.GenerateTree(shared).Value
+}
+
+// GenerateTree shrinks structs field by field, in the order of the fields of T
+func (s *structOf[T]) GenerateTree(src *Source) Tree[T] {
+	trees := make([]Tree[reflect.Value], len(s.fields))
+	for i, f := range s.fields {
+		trees[i] = reflectTree(f.gen, src)
+	}
+	return MapTree(s.fieldsTree(trees), func(v reflect.Value) T { return v.Interface().(T) })
+}
+
+func (s *structOf[T]) fieldsTree(trees []Tree[reflect.Value]) Tree[reflect.Value] {
+	v := reflect.New(s.t).Elem()
+	for i, f := range s.fields {
+		v.Field(f.index).Set(trees[i].Value)
+	}
+	return NewTree(v, func() []Tree[reflect.Value] {
+		var res []Tree[reflect.Value]
+		for i, tree := range trees {
+			for _, c := range tree.Shrinks() {
+				replaced := make([]Tree[reflect.Value], len(trees))
+				copy(replaced, trees)
+				replaced[i] = c
+				res = append(res, s.fieldsTree(replaced))
+			}
+		}
+		return res
+	})
+}
+
+func (s *structOf[T]) GenerateN(n uint) []T {
+	res := make([]T, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = s.GenerateOne()
+	}
+	return res
+}
+
+// StructOf generates structs of type T, generating each field named in fieldGens with the Generator of the field type it maps to,
+// e.g. StructOf[Person](map[string]any{"Name": nameGen, "Age": Between(0, 120)}).
+// Other fields are left to their zero value, use gopbt.StructOf to generate them the way a session does.
+// It panics if T is not a struct, or if a name is not an exported field of T, or is not mapped to a Generator of the field type.
+func StructOf[T any](fieldGens map[string]any) Generator[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("StructOf: %s is not a struct", t))
+	}
+	for name := range fieldGens {
+		if f, ok := t.FieldByName(name); !ok || len(f.Index) != 1 {
+			panic(fmt.Errorf("StructOf: %s has no field %s", t, name))
+		}
+	}
+	s := &structOf[T]{t: t}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		g, ok := fieldGens[field.Name]
+		if !ok {
+			continue
+		}
+		if !field.IsExported() {
+			panic(fmt.Errorf("StructOf: field %s.%s is unexported", t, field.Name))
+		}
+		if generated, ok := generatedType(g); !ok || !generated.AssignableTo(field.Type) {
+			panic(fmt.Errorf("StructOf: %T is not a Generator of %s for field %s.%s", g, field.Type, t, field.Name))
+		}
+		s.fields = append(s.fields, structField{i, reflect.ValueOf(g)})
+	}
+	return s
+}
+
+// generatedType returns the type of the values generated by g, if g is a Generator
+func generatedType(g any) (reflect.Type, bool) {
+	if g == nil {
+		return nil, false
+	}
+	m, ok := reflect.TypeOf(g).MethodByName("GenerateOne")
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		return nil, false
+	}
+	return m.Type.Out(0), true
+}
+
+// reflectTree is GenerateTree for a Generator whose type is only known at run time
+func reflectTree(g reflect.Value, src *Source) Tree[reflect.Value] {
+	if m := g.MethodByName("GenerateTree"); m.IsValid() && m.Type().NumIn() == 1 && m.Type().In(0) == reflect.TypeOf(src) && m.Type().NumOut() == 1 {
+		if tree := m.Call([]reflect.Value{reflect.ValueOf(src)})[0]; tree.Kind() == reflect.Struct && tree.MethodByName("Shrinks").IsValid() {
+			return valueTree(tree)
+		}
+	}
+	// generators which do not implement TreeGenerator draw from the shared source, see GenerateTree
+	value := g.MethodByName("GenerateOne").Call(nil)[0]
+	if m := g.MethodByName("Shrink"); m.IsValid() && m.Type().NumIn() == 1 && m.Type().NumOut() == 1 {
+		return Unfold(value, func(v reflect.Value) []reflect.Value {
+			candidates := m.Call([]reflect.Value{v})[0]
+			res := make([]reflect.Value, candidates.Len())
+			for i := range res {
+				res[i] = candidates.Index(i)
+			}
+			return res
+		})
+	}
+	return Leaf(value)
+}
+
+// valueTree converts tree, a Tree whose type is only known at run time
+func valueTree(tree reflect.Value) Tree[reflect.Value] {
+	return NewTree(tree.FieldByName("Value"), func() []Tree[reflect.Value] {
+		shrinks := tree.MethodByName("Shrinks").Call(nil)[0]
+		res := make([]Tree[reflect.Value], shrinks.Len())
+		for i := range res {
+			res[i] = valueTree(shrinks.Index(i))
+		}
+		return res
+	})
+}
@@ -12,6 +12,10 @@ func (t timeBetween) GenerateOne() time.Time {
 	return t.start.Add(time.Duration(newDuration))
 }
 
+func (t timeBetween) GenerateTree(src *Source) Tree[time.Time] {
+	return MapTree(GenerateTree(t.durationGen, src), func(d int64) time.Time { return t.start.Add(time.Duration(d)) })
+}
+
 func (t timeBetween) GenerateN(n uint) []time.Time {
 	res := make([]time.Time, n)
 	for i := uint(0); i < n; i++ {
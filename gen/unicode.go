@@ -0,0 +1,169 @@
+package gen
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// ------ runes ------
+
+// runeRange holds the runes lo, lo+stride, lo+2*stride, ... up to hi inclusive, like the ranges of unicode.RangeTable
+type runeRange struct {
+	lo, hi, stride rune
+}
+
+func (r runeRange) count() uint64 { return uint64((r.hi-r.lo)/r.stride) + 1 }
+
+type runeFrom struct {
+	ranges []runeRange
+	total  uint64
+}
+
+func (r *runeFrom) GenerateOne() rune {
+	return r.generate(shared)
+}
+
+func (r *runeFrom) GenerateTree(src *Source) Tree[rune] {
+	return Unfold(r.generate(src), r.Shrink)
+}
+
+func (r *runeFrom) generate(src *Source) rune {
+	n := randUint64n(src, r.total)
+	for _, rr := range r.ranges {
+		if n < rr.count() {
+			return rr.lo + rune(n)*rr.stride
+		}
+		n -= rr.count()
+	}
+	return r.ranges[len(r.ranges)-1].hi
+}
+
+func (r *runeFrom) GenerateN(n uint) []rune {
+	res := make([]rune, n)
+	for i := uint(0); i < n; i++ {
+		res[i] = r.GenerateOne()
+	}
+	return res
+}
+
+// Shrink proposes the first rune of the ranges coming before the one of value, and then runes of its range closer to its first rune
+func (r *runeFrom) Shrink(value rune) []rune {
+	var res []rune
+	for _, rr := range r.ranges {
+		if value >= rr.lo && value <= rr.hi && (value-rr.lo)%rr.stride == 0 {
+			for _, k := range towards(0, (value-rr.lo)/rr.stride) {
+				res = append(res, rr.lo+k*rr.stride)
+			}
+			return res
+		}
+		res = append(res, rr.lo)
+	}
+	return nil
+}
+
+// RuneFrom generates the runes of the Unicode tables uniformly, e.g. RuneFrom(unicode.Greek, unicode.Cyrillic).
+// Runes shrink towards the first rune of the first table. It panics if the tables hold no rune.
+func RuneFrom(tables ...*unicode.RangeTable) Generator[rune] {
+	r := &runeFrom{}
+	for _, table := range tables {
+		for _, rr := range table.R16 {
+			r.ranges = append(r.ranges, runeRange{rune(rr.Lo), rune(rr.Hi), rune(rr.Stride)})
+		}
+		for _, rr := range table.R32 {
+			r.ranges = append(r.ranges, runeRange{rune(rr.Lo), rune(rr.Hi), rune(rr.Stride)})
+		}
+	}
+	for _, rr := range r.ranges {
+		r.total += rr.count()
+	}
+	if r.total == 0 {
+		panic(fmt.Errorf("RuneFrom: no rune in the tables"))
+	}
+	return r
+}
+
+// asciiTable holds the printable ASCII characters, letters and digits first so that runes shrink towards 'a'
+var asciiTable = &unicode.RangeTable{R16: []unicode.Range16{
+	{'a', 'z', 1}, {'A', 'Z', 1}, {'0', '9', 1}, {' ', '/', 1}, {':', '@', 1}, {'[', '`', 1}, {'{', '~', 1},
+}}
+
+// emojiTable holds the main emoji blocks, which unicode has no table for
+var emojiTable = &unicode.RangeTable{R16: []unicode.Range16{
+	{0x2600, 0x26ff, 1}, {0x2700, 0x27bf, 1},
+}, R32: []unicode.Range32{
+	{0x1f300, 0x1f5ff, 1}, {0x1f600, 0x1f64f, 1}, {0x1f680, 0x1f6ff, 1}, {0x1f900, 0x1f9ff, 1},
+}}
+
+// validTable holds every valid rune, that is every code point but surrogates
+var validTable = &unicode.RangeTable{R16: []unicode.Range16{
+	{0, 0xd7ff, 1}, {0xe000, 0xffff, 1},
+}, R32: []unicode.Range32{
+	{0x10000, unicode.MaxRune, 1},
+}}
+
+// ASCIIRune generates printable ASCII characters, shrinking towards 'a'
+var ASCIIRune = RuneFrom(asciiTable)
+
+// LetterRune generates letters of any script
+var LetterRune = RuneFrom(unicode.Letter)
+
+// HanRune generates Han characters, as used by Chinese and Japanese
+var HanRune = RuneFrom(unicode.Han)
+
+// EmojiRune generates emoji and pictographic symbols
+var EmojiRune = RuneFrom(emojiTable)
+
+// CombiningMarkRune generates nonspacing marks, which combine with the rune before them such as accents
+var CombiningMarkRune = RuneFrom(unicode.Mn)
+
+// ValidRune generates any valid rune, that is any code point but surrogates
+var ValidRune = RuneFrom(validTable)
+
+// ArbitraryRune generates valid runes, mostly printable ASCII characters, and otherwise letters, Han characters, emoji, combining marks
+// or any other valid rune
+var ArbitraryRune = Frequency(
+	WeightedGen[rune]{12, ASCIIRune},
+	WeightedGen[rune]{1, LetterRune},
+	WeightedGen[rune]{1, HanRune},
+	WeightedGen[rune]{1, EmojiRune},
+	WeightedGen[rune]{1, CombiningMarkRune},
+	WeightedGen[rune]{1, ValidRune},
+)
+
+// ------ strings ------
+
+// StringOf generates strings of runes generated by runes, with a length in runes between minLength and maxLength inclusive.
+// Strings shrink by removing runes, and then by shrinking each rune.
+func StringOf(runes Generator[rune], minLength, maxLength uint) Generator[string] {
+	return Using(SliceOf(runes, minLength, maxLength), func(rs []rune) string { return string(rs) })
+}
+
+// UTF8Bytes generates valid UTF-8 encoded byte strings of runes generated by ArbitraryRune, with a length in runes between minLength and maxLength inclusive
+func UTF8Bytes(minLength, maxLength uint) Generator[[]byte] {
+	return Using(SliceOf(ArbitraryRune, minLength, maxLength), func(rs []rune) []byte { return []byte(string(rs)) })
+}
+
+// invalidUTF8 holds byte sequences which are invalid UTF-8 wherever they appear between runes
+var invalidUTF8 = [][]byte{
+	{0xff},                   // never appears in UTF-8
+	{0x80},                   // continuation byte without a leading byte
+	{0xe2, 0x82},             // truncated encoding
+	{0xc0, 0xaf},             // overlong encoding of '/'
+	{0xed, 0xa0, 0x80},       // encoded surrogate
+	{0xf4, 0x90, 0x80, 0x80}, // encoding beyond unicode.MaxRune
+}
+
+// InvalidUTF8Bytes generates byte strings which are not valid UTF-8, made of runes generated by ArbitraryRune with a length in runes between
+// minLength and maxLength inclusive, and an invalid byte sequence inserted between two of them.
+// Byte strings shrink by moving the invalid sequence to the start, then by removing and shrinking runes.
+func InvalidUTF8Bytes(minLength, maxLength uint) Generator[[]byte] {
+	return Map3(Between(0, int(maxLength)+1), OneOf(invalidUTF8...), SliceOf(ArbitraryRune, minLength, maxLength),
+		func(position int, invalid []byte, rs []rune) []byte {
+			if position > len(rs) {
+				position = len(rs)
+			}
+			res := []byte(string(rs[:position]))
+			res = append(res, invalid...)
+			return append(res, string(rs[position:])...)
+		})
+}
@@ -1,10 +1,92 @@
 package gopbt
 
+import (
+	"reflect"
+	"sync"
+)
+
+// typeGenMapping holds the generators of a session.
+// When generating a struct field, generators are looked up by field first, then by the name in the `gopbt` tag of the field, then by type.
 type typeGenMapping struct {
-	// todo, add named generators in addition to type generators. name priority should be higher than type name
-	generatorMapping map[string]anyGen
+	// mu guards the generators, which are also added while checking properties, possibly from parallel subtests
+	mu sync.RWMutex
+	// generators are keyed by the type itself rather than its name, as unnamed types have no name, and types of different packages can share a name
+	generatorMapping map[reflect.Type]anyGen
+
+	// fieldGenerators are keyed by struct type, and then by field name
+	fieldGenerators map[reflect.Type]map[string]anyGen
+
+	// tagGenerators are keyed by the name used in `gopbt:"name"` struct tags
+	tagGenerators map[string]anyGen
+
+	// implementations are keyed by interface type
+	implementations map[reflect.Type][]implementation
+}
+
+func newTypeGenMapping(generators map[reflect.Type]anyGen) *typeGenMapping {
+	return &typeGenMapping{
+		generatorMapping: generators,
+		fieldGenerators:  make(map[reflect.Type]map[string]anyGen),
+		tagGenerators:    make(map[string]anyGen),
+		implementations:  make(map[reflect.Type][]implementation),
+	}
+}
+
+func (mapping *typeGenMapping) setGenerator(t reflect.Type, g anyGen) {
+	mapping.mu.Lock()
+	defer mapping.mu.Unlock()
+	mapping.generatorMapping[t] = g
+}
+
+func (mapping *typeGenMapping) generator(t reflect.Type) (anyGen, bool) {
+	mapping.mu.RLock()
+	defer mapping.mu.RUnlock()
+	g, ok := mapping.generatorMapping[t]
+	return g, ok
+}
+
+func (mapping *typeGenMapping) setFieldGenerator(structType reflect.Type, field string, g anyGen) {
+	mapping.mu.Lock()
+	defer mapping.mu.Unlock()
+	if mapping.fieldGenerators[structType] == nil {
+		mapping.fieldGenerators[structType] = make(map[string]anyGen)
+	}
+	mapping.fieldGenerators[structType][field] = g
+}
+
+func (mapping *typeGenMapping) setTagGenerator(name string, g anyGen) {
+	mapping.mu.Lock()
+	defer mapping.mu.Unlock()
+	mapping.tagGenerators[name] = g
+}
+
+func (mapping *typeGenMapping) addImplementation(iface reflect.Type, impl implementation) {
+	mapping.mu.Lock()
+	defer mapping.mu.Unlock()
+	mapping.implementations[iface] = append(mapping.implementations[iface], impl)
+}
+
+func (mapping *typeGenMapping) implementationsOf(iface reflect.Type) []implementation {
+	mapping.mu.RLock()
+	defer mapping.mu.RUnlock()
+	return mapping.implementations[iface]
+}
+
+// namedGeneratorFor returns the generator registered for the field of structType, either by field name or by tag
+func (mapping *typeGenMapping) namedGeneratorFor(structType reflect.Type, field reflect.StructField) (anyGen, bool) {
+	mapping.mu.RLock()
+	defer mapping.mu.RUnlock()
+	if g, ok := mapping.fieldGenerators[structType][field.Name]; ok {
+		return g, true
+	}
+	if name := parseTag(field.Tag).name; name != "" {
+		g, ok := mapping.tagGenerators[name]
+		return g, ok
+	}
+	return nil, false
 }
 
-func (mapping *typeGenMapping) setGenerator(typeName string, g anyGen) {
-	mapping.generatorMapping[typeName] = g
+// typeOf returns the reflect.Type of T, which unlike reflect.TypeOf(*new(T)) also works for interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
 }
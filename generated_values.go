@@ -0,0 +1,126 @@
+package gopbt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// generatedValues holds the functions and channels generated for a check case, to describe them in counterexamples
+type generatedValues struct {
+	mu sync.Mutex
+	// funcs maps the funcs created by reflect.MakeFunc to their implementation
+	funcs map[unsafe.Pointer]*generatedFunc
+	// evaluation counts the evaluations of the property, to tell which calls to generated functions were made by the last one
+	evaluation uint64
+
+	// publishesChans is set for check cases, which publish their channels for deepCopy to copy them, and release them once done.
+	// chans holds the addresses of the published channels.
+	publishesChans bool
+	chans          []uintptr
+}
+
+func newGeneratedValues(publishesChans bool) *generatedValues {
+	return &generatedValues{funcs: make(map[unsafe.Pointer]*generatedFunc), publishesChans: publishesChans}
+}
+
+func (gv *generatedValues) registerChan(c reflect.Value, gc *generatedChan) {
+	if !gv.publishesChans {
+		return
+	}
+	gv.mu.Lock()
+	gv.chans = append(gv.chans, c.Pointer())
+	gv.mu.Unlock()
+
+	publishedChansMu.Lock()
+	defer publishedChansMu.Unlock()
+	publishedChans[c.Pointer()] = publishedChan{c, gc}
+}
+
+// release forgets the channels published by gv, once its check case is done
+func (gv *generatedValues) release() {
+	gv.mu.Lock()
+	chans := gv.chans
+	gv.chans = nil
+	gv.mu.Unlock()
+
+	publishedChansMu.Lock()
+	defer publishedChansMu.Unlock()
+	for _, c := range chans {
+		delete(publishedChans, c)
+	}
+}
+
+// beginEvaluation is called before evaluating the property, so that generated functions only describe the calls of one evaluation
+func (gv *generatedValues) beginEvaluation() {
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	gv.evaluation++
+}
+
+// keepFailingCalls is called after an evaluation of the property failed, to describe generated functions by the calls which made it fail
+func (gv *generatedValues) keepFailingCalls() {
+	gv.mu.Lock()
+	evaluation := gv.evaluation
+	funcs := make([]*generatedFunc, 0, len(gv.funcs))
+	for _, gf := range gv.funcs {
+		funcs = append(funcs, gf)
+	}
+	// functions are not locked while holding gv, as calling them may register the functions they return
+	gv.mu.Unlock()
+
+	for _, gf := range funcs {
+		gf.mu.Lock()
+		if gf.evaluation == evaluation {
+			gf.failing = gf.recent
+		} else {
+			gf.failing = nil
+		}
+		gf.mu.Unlock()
+	}
+}
+
+func (gv *generatedValues) currentEvaluation() uint64 {
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	return gv.evaluation
+}
+
+func (gv *generatedValues) describeTuple(values []reflect.Value) string {
+	if len(values) == 1 {
+		return gv.describe(values[0])
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = gv.describe(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// describe formats a generated value for counterexamples, printing generated functions as lookup tables and generated channels by their elements
+func (gv *generatedValues) describe(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Func:
+		if gf, ok := gv.lookupFunc(v); ok {
+			return gf.describe()
+		}
+	case reflect.Chan:
+		if gc, ok := lookupChan(v); ok {
+			return gc.describe()
+		}
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("%#v", v.Interface())
+}
+
+func (gv *generatedValues) describeAll(values []reflect.Value) []string {
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i] = gv.describe(v)
+	}
+	return res
+}
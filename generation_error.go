@@ -0,0 +1,47 @@
+package gopbt
+
+import (
+	"fmt"
+	"strings"
+	"testing/quick"
+)
+
+// generationError is the panic value of adhoc generators failing to generate a value, or of any generator panicking while nested in one.
+// path leads from the generated type to the type that could not be generated, e.g. `.Users[].Address`.
+type generationError struct {
+	path  []string
+	cause any
+}
+
+func (e *generationError) Error() string {
+	if len(e.path) == 0 {
+		return fmt.Sprint(e.cause)
+	}
+	return fmt.Sprintf("at %s: %v", strings.Join(e.path, ""), e.cause)
+}
+
+// prependPath returns the panic value r as a *generationError, with segment prepended to its path
+func prependPath(r any, segment string) *generationError {
+	if genErr, ok := r.(*generationError); ok {
+		return &generationError{path: append([]string{segment}, genErr.path...), cause: genErr.cause}
+	}
+	return &generationError{path: []string{segment}, cause: r}
+}
+
+// annotateGenerationPanic must be deferred directly, it adds segment to the path of any panic occurring while generating a nested value
+func annotateGenerationPanic(segment string) {
+	if r := recover(); r != nil {
+		panic(prependPath(r, segment))
+	}
+}
+
+// recoverGenerationPanic must be deferred directly, it turns any panic occurring while generating the value of an argument into a setup error stored in err
+func recoverGenerationPanic(err *error, argIndex int, argType fmt.Stringer) {
+	if r := recover(); r != nil {
+		genErr, ok := r.(*generationError)
+		if !ok {
+			genErr = &generationError{cause: r}
+		}
+		*err = quick.SetupError(fmt.Sprintf("generator of argument #%d (%s) panicked: %s", argIndex, argType, genErr))
+	}
+}
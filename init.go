@@ -2,6 +2,7 @@ package gopbt
 
 import (
 	"flag"
+	"path/filepath"
 	"reflect"
 	"testing/quick"
 
@@ -12,27 +13,50 @@ import (
 var defaultMaxCount *int = flag.Int("gopbtchecks", 100, "The default number of iterations for each check")
 var defaultConfig quick.Config
 
+var seedFlag *int64 = flag.Int64("gopbtseed", 0, "The seed used by every check, to reproduce a failure (a random seed when unset)")
+
+// seedFlagSet tells whether -gopbtseed was passed, so that any seed, including 0, can be requested
+func seedFlagSet() (set bool) {
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == "gopbtseed"
+	})
+	return
+}
+
+var failureDatabaseRoot *string = flag.String("gopbtdb", filepath.Join("testdata", "gopbt"), "The directory where the failing cases of each test are persisted")
+
+// seedEnv is the environment variable used to set the seed of every check when the -gopbtseed flag is not set
+const seedEnv = "GOPBT_SEED"
+
 // todo, add this to init
-var primitiveGenerators map[string]anyGen
+var primitiveGenerators map[reflect.Type]anyGen
 
-var defaultAlphabet string
 var defaultStringGen gen.Generator[string]
 
 func init() {
-	defaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-_=+?/`~\"\\:;"
-	defaultStringGen = gen.StringGen(defaultAlphabet, uint(0), uint(complexSize))
-
-	primitiveGenerators = map[string]anyGen{
-		reflect.TypeOf(0).Name():          wrap(gen.ArbitraryInt),
-		reflect.TypeOf(int32(0)).Name():   wrap(gen.ArbitraryInt32),
-		reflect.TypeOf(int64(0)).Name():   wrap(gen.ArbitraryInt64),
-		reflect.TypeOf(uint(0)).Name():    wrap(gen.ArbitraryUint),
-		reflect.TypeOf(uint16(0)).Name():  wrap(gen.ArbitraryUint16),
-		reflect.TypeOf(uint32(0)).Name():  wrap(gen.ArbitraryUint32),
-		reflect.TypeOf(uint64(0)).Name():  wrap(gen.ArbitraryUint64),
-		reflect.TypeOf(float32(0)).Name(): wrap(gen.ArbitraryFloat32),
-		reflect.TypeOf(float64(0)).Name(): wrap(gen.ArbitraryFloat64),
-		reflect.TypeOf('r').Name():        wrap(gen.ArbitraryRune),
-		reflect.TypeOf("").Name():         wrap(defaultStringGen),
+	// strings mix ASCII with multi-byte characters, as text handling mostly breaks on non-ASCII input
+	defaultStringGen = gen.Sized(func(size int) gen.Generator[string] {
+		return gen.StringOf(gen.ArbitraryRune, uint(0), uint(size))
+	})
+
+	// byte and rune are aliases of uint8 and int32, which share their generators
+	primitiveGenerators = map[reflect.Type]anyGen{
+		reflect.TypeOf(false):         wrap(gen.ArbitraryBool),
+		reflect.TypeOf(0):             wrap(gen.ArbitraryInt),
+		reflect.TypeOf(int8(0)):       wrap(gen.ArbitraryInt8),
+		reflect.TypeOf(int16(0)):      wrap(gen.ArbitraryInt16),
+		reflect.TypeOf(int32(0)):      wrap(gen.ArbitraryInt32),
+		reflect.TypeOf(int64(0)):      wrap(gen.ArbitraryInt64),
+		reflect.TypeOf(uint(0)):       wrap(gen.ArbitraryUint),
+		reflect.TypeOf(uint8(0)):      wrap(gen.ArbitraryUint8),
+		reflect.TypeOf(uint16(0)):     wrap(gen.ArbitraryUint16),
+		reflect.TypeOf(uint32(0)):     wrap(gen.ArbitraryUint32),
+		reflect.TypeOf(uint64(0)):     wrap(gen.ArbitraryUint64),
+		reflect.TypeOf(uintptr(0)):    wrap(gen.ArbitraryUintptr),
+		reflect.TypeOf(float32(0)):    wrap(gen.ArbitraryFloat32),
+		reflect.TypeOf(float64(0)):    wrap(gen.ArbitraryFloat64),
+		reflect.TypeOf(complex64(0)):  wrap(gen.ArbitraryComplex64),
+		reflect.TypeOf(complex128(0)): wrap(gen.ArbitraryComplex128),
+		reflect.TypeOf(""):            wrap(defaultStringGen),
 	}
 }
@@ -0,0 +1,74 @@
+package gopbt
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// implementation is a concrete type registered for an interface type, generated by g, or by the session when g is nil
+type implementation struct {
+	t reflect.Type
+	g anyGen
+}
+
+// defaultAnyImplementations are used for empty interfaces such as any, unless implementations are registered for them
+var defaultAnyImplementations = []implementation{
+	{t: reflect.TypeOf(0)},
+	{t: reflect.TypeOf(0.0)},
+	{t: reflect.TypeOf("")},
+	{t: reflect.TypeOf(false)},
+}
+
+// AddImpl registers T as an implementation of the interface I, values of I are then generated as values of one of its implementations.
+// Values of T are generated by the session. It panics if I is not an interface, or if T does not implement it.
+func AddImpl[I any, T any](s *Session) {
+	s.mapping.addImplementation(checkImplements[I, T]("AddImpl"), implementation{t: typeOf[T]()})
+}
+
+// AddImplGen is like AddImpl, but values of T are generated by g
+func AddImplGen[I any, T any](s *Session, g gen.Generator[T]) {
+	s.mapping.addImplementation(checkImplements[I, T]("AddImplGen"), implementation{typeOf[T](), wrap(g)})
+}
+
+func checkImplements[I any, T any](caller string) reflect.Type {
+	iface, impl := typeOf[I](), typeOf[T]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Errorf("%s: %s is not an interface", caller, iface))
+	}
+	if !impl.Implements(iface) {
+		panic(fmt.Errorf("%s: %s does not implement %s", caller, impl, iface))
+	}
+	return iface
+}
+
+func (s *Session) implementationsOf(t reflect.Type) []implementation {
+	if impls := s.mapping.implementationsOf(t); len(impls) > 0 {
+		return impls
+	}
+	if t.NumMethod() == 0 {
+		return defaultAnyImplementations
+	}
+	return nil
+}
+
+// interfaceTree generates a value of the interface type t as a value of one of its implementations, picked at random.
+// The value shrinks within its implementation.
+func (gn *generation) interfaceTree(t reflect.Type, size int) (tree gen.Tree[reflect.Value], ok bool) {
+	impls := gn.s.implementationsOf(t)
+	if len(impls) == 0 {
+		return
+	}
+	impl := impls[draw(gn.src, gen.Between(0, len(impls)))]
+	if impl.g != nil {
+		tree = gn.tree(impl.g)
+	} else if tree, ok = gn.nestedTree(impl.t, size, ".("+impl.t.String()+")"); !ok {
+		return
+	}
+	return gen.MapTree(tree, func(v reflect.Value) reflect.Value {
+		i := reflect.New(t).Elem()
+		i.Set(v)
+		return i
+	}), true
+}
@@ -0,0 +1,101 @@
+package gopbt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// shortModeDivisor divides the number of checks of a property when tests run with -short
+const shortModeDivisor = 10
+
+// Property checks f as a subtest of t named name, using a session with primitive and adhoc generators.
+// It reports whether the property holds, see Session.Property.
+func Property(t *testing.T, name string, f FunctionReturningBool) bool {
+	t.Helper()
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	return s.Property(t, name, f)
+}
+
+// Property checks f as a subtest of t named name, failing the subtest with the shrunk counterexample when f does not hold.
+// Failing cases are persisted in FailureDatabaseFor(<subtest name>) or in a subdirectory of the FailureDatabase of the session, and are replayed first on later runs.
+// Only a tenth of the configured number of checks runs in -short mode.
+// It reports whether the property holds.
+func (s *Session) Property(t *testing.T, name string, f FunctionReturningBool) bool {
+	t.Helper()
+	return t.Run(name, func(t *testing.T) {
+		t.Helper()
+		if s.Parallel {
+			t.Parallel()
+		}
+
+		conf := *s.config()
+		if testing.Short() {
+			conf.MaxCount = getMaxCount(&conf) / shortModeDivisor
+			if conf.MaxCount == 0 {
+				conf.MaxCount = 1
+			}
+		}
+		discarded, seed, err := s.check(f, &conf, s.failureDatabase(t.Name()))
+		switch e := err.(type) {
+		case nil:
+			if discarded > 0 {
+				t.Logf("passed with seed %d, %d cases discarded", seed, discarded)
+			} else {
+				t.Logf("passed with seed %d", seed)
+			}
+		case *CheckError:
+			t.Error(propertyReport(e))
+		default:
+			t.Fatal(err)
+		}
+	})
+}
+
+// propertyReport describes a failure of a property, listing each argument on its own line
+func propertyReport(e *CheckError) string {
+	var b strings.Builder
+	if e.ReplayedFrom != "" {
+		fmt.Fprintf(&b, "property failed on a case replayed from %s\n", e.ReplayedFrom)
+	} else {
+		if e.Discarded > 0 {
+			fmt.Fprintf(&b, "property failed after %d checks, %d cases discarded\n", e.Count, e.Discarded)
+		} else {
+			fmt.Fprintf(&b, "property failed after %d checks\n", e.Count)
+		}
+		fmt.Fprintf(&b, "seed: %d (reproduce with -gopbtseed=%d or %s=%d)\n", e.Seed, e.Seed, seedEnv, e.Seed)
+	}
+	if e.Shrinks > 0 {
+		fmt.Fprintf(&b, "counterexample (shrunk in %d steps):\n", e.Shrinks)
+	} else {
+		fmt.Fprintf(&b, "counterexample:\n")
+	}
+	writeArguments(&b, e.In, e.inDescriptions)
+	if e.Message != "" {
+		fmt.Fprintf(&b, "failure: %s\n", e.Message)
+	}
+	if e.Stack != "" {
+		fmt.Fprintf(&b, "stack:\n%s\n", strings.TrimSuffix(e.Stack, "\n"))
+	}
+	if e.Shrinks > 0 {
+		fmt.Fprintf(&b, "original input:\n")
+		writeArguments(&b, e.Original, e.originalDescriptions)
+	}
+	if e.SaveErr != nil {
+		fmt.Fprintf(&b, "failed to save to the failure database: %s\n", e.SaveErr)
+	} else if e.SavedTo != "" {
+		fmt.Fprintf(&b, "saved to %s\n", e.SavedTo)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeArguments(b *strings.Builder, args []any, descriptions []string) {
+	for i, arg := range args {
+		if descriptions != nil {
+			fmt.Fprintf(b, "  #%d (%T): %s\n", i, arg, descriptions[i])
+		} else {
+			fmt.Fprintf(b, "  #%d (%T): %#v\n", i, arg, arg)
+		}
+	}
+}
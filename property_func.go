@@ -0,0 +1,105 @@
+package gopbt
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"testing/quick"
+)
+
+var (
+	boolType  = reflect.TypeOf(true)
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	tType     = reflect.TypeOf(&T{})
+)
+
+// property is a function under test, which is either a func(...) bool, a func(...) error, or a func(...) failing by panicking.
+// Any of them may also take a *T as their first argument.
+type property struct {
+	f      reflect.Value
+	takesT bool
+}
+
+func newProperty(fVal reflect.Value, fType reflect.Type) (*property, error) {
+	switch {
+	case fType.NumOut() > 1:
+		return nil, quick.SetupError("function returns more than one value")
+	case fType.NumOut() == 1 && fType.Out(0) != boolType && fType.Out(0) != errorType:
+		return nil, quick.SetupError("function does not return a bool or an error")
+	}
+	return &property{f: fVal, takesT: fType.NumIn() > 0 && fType.In(0) == tType}, nil
+}
+
+// inputTypes returns the types of the arguments that must be generated for the property
+func (p *property) inputTypes() []reflect.Type {
+	fType := p.f.Type()
+	var types []reflect.Type
+	for i := 0; i < fType.NumIn(); i++ {
+		if i == 0 && p.takesT {
+			continue
+		}
+		types = append(types, fType.In(i))
+	}
+	return types
+}
+
+// propertyFailure describes why a property failed for a set of arguments
+type propertyFailure struct {
+	message string
+	// panicValue and stack are set when the property panicked
+	panicValue any
+	stack      string
+}
+
+// run evaluates the property for args, returning a nil failure if the property holds, or if args are discarded by Assume.
+// Panics of the property are recovered and reported as failures, along with their stack trace.
+func (p *property) run(args []reflect.Value) (failure *propertyFailure, discarded bool) {
+	t := &T{}
+	if p.takesT {
+		args = append([]reflect.Value{reflect.ValueOf(t)}, args...)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isDiscard := r.(discard); isDiscard {
+				discarded = true
+				return
+			}
+			if _, isFailNow := r.(failNow); isFailNow {
+				failure = &propertyFailure{message: t.message()}
+				return
+			}
+			failure = &propertyFailure{
+				message:    joinMessages(t.message(), fmt.Sprintf("panic: %v", r)),
+				panicValue: r,
+				stack:      string(debug.Stack()),
+			}
+		}
+	}()
+
+	out := p.f.Call(args)
+	failed := t.Failed()
+	message := t.message()
+
+	if len(out) > 0 {
+		switch result := out[0].Interface().(type) {
+		case bool:
+			failed = failed || !result
+		case error:
+			failed = true
+			message = joinMessages(message, result.Error())
+		}
+	}
+
+	if failed {
+		return &propertyFailure{message: message}, false
+	}
+	return nil, false
+}
+
+func joinMessages(first, second string) string {
+	if first == "" {
+		return second
+	}
+	return first + "\n" + second
+}
@@ -0,0 +1,88 @@
+package gopbt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+func TestPropertyRunsAsSubtest(t *testing.T) {
+	s := NewSession()
+	SetGen(s, gen.Between(0, 100))
+	s.FailureDatabase = t.TempDir()
+
+	ran := false
+	holds := s.Property(t, "ints are in range", func(i int) bool {
+		ran = true
+		return i >= 0 && i < 100
+	})
+
+	if !holds || !ran {
+		t.Error("expected the property to be checked and to hold")
+	}
+}
+
+func TestPassingChecksReportTheirSeed(t *testing.T) {
+	s := NewSession()
+	SetGen(s, gen.Between(0, 100))
+	s.Seed = 42
+
+	if _, seed, err := s.check(func(i int) bool { return i < 100 }, nil, t.TempDir()); err != nil || seed != 42 {
+		t.Errorf("expected the passing check to report seed 42, got %d and %v", seed, err)
+	}
+}
+
+func TestConcurrentChecksAreReproducible(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	s.Seed = 42
+
+	noLongSlices := func(ints []int, str string) bool { return len(ints)+len(str) < 60 }
+
+	// checks running at the same time, like the ones of parallel properties, do not draw from each other's source
+	failures := make([]*CheckError, 4)
+	var wg sync.WaitGroup
+	for i := range failures {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			_, _, err := s.check(noLongSlices, nil, dir)
+			failures[i], _ = err.(*CheckError)
+		}(i, t.TempDir())
+	}
+	wg.Wait()
+
+	for _, f := range failures {
+		if f == nil || formatInputs(f.Original, nil) != formatInputs(failures[0].Original, nil) {
+			t.Fatalf("expected concurrent checks with the same seed to fail identically, got %v", failures)
+		}
+	}
+}
+
+func TestPropertyReport(t *testing.T) {
+	report := propertyReport(&CheckError{
+		Count:    3,
+		Seed:     7,
+		In:       []any{0, "a"},
+		Original: []any{12, "abc"},
+		Shrinks:  4,
+		SavedTo:  "testdata/gopbt/TestSomething/0123",
+	})
+
+	expected := []string{
+		"property failed after 3 checks",
+		"seed: 7 (reproduce with -gopbtseed=7 or GOPBT_SEED=7)",
+		"counterexample (shrunk in 4 steps):",
+		`  #0 (int): 0`,
+		`  #1 (string): "a"`,
+		"original input:",
+		`  #0 (int): 12`,
+		`  #1 (string): "abc"`,
+		"saved to testdata/gopbt/TestSomething/0123",
+	}
+	if actual := strings.Split(report, "\n"); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
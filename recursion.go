@@ -0,0 +1,83 @@
+package gopbt
+
+import (
+	"reflect"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+const (
+	// defaultMaxDepth is the default nesting of a recursive type in itself, beyond which recursive references are always leaves
+	defaultMaxDepth = 6
+
+	// defaultLeafProbability is the default probability for a recursive reference to be a leaf, at the first level of nesting
+	defaultLeafProbability = 0.25
+)
+
+// enter marks t as being generated until leave is called, so that references to t met meanwhile are known to be recursive
+func (gn *generation) enter(t reflect.Type) (leave func()) {
+	if gn.generating == nil {
+		gn.generating = make(map[reflect.Type]int)
+	}
+	gn.generating[t]++
+	return func() { gn.generating[t]-- }
+}
+
+// recursionDepth returns the number of values of the type referenced by t being generated,
+// e.g. 2 for *Node or []Node while generating a Node nested in another Node, or for L while generating an L nested in another L with `type L []L`
+func (gn *generation) recursionDepth(t reflect.Type) int {
+	// named containers may be their own elements, so types already met end the walk
+	seen := make(map[reflect.Type]bool)
+	for !seen[t] {
+		seen[t] = true
+		if depth := gn.generating[t]; depth > 0 {
+			return depth
+		}
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+			t = t.Elem()
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// isLeaf decides whether the reference t is generated as a leaf, that is a nil pointer or an empty slice or map.
+// Only recursive references can be leaves, with a probability growing from LeafProbability at the first level of nesting to 1 at MaxDepth,
+// which guarantees that generating recursive types terminates.
+func (gn *generation) isLeaf(t reflect.Type) bool {
+	depth := gn.recursionDepth(t)
+	if depth == 0 {
+		return false
+	}
+	maxDepth := gn.s.maxDepth()
+	if depth >= maxDepth {
+		return true
+	}
+	p := gn.s.leafProbability()
+	p += (1 - p) * float64(depth-1) / float64(maxDepth-1)
+	return draw(gn.src, gen.Between(0.0, 1.0)) < p
+}
+
+// levelSize decays size for each level of nesting of t in itself, so that nested values are smaller than the values they are nested in
+func (gn *generation) levelSize(t reflect.Type, size int) int {
+	return size >> gn.generating[t]
+}
+
+func (s *Session) maxDepth() int {
+	if s.MaxDepth > 0 {
+		return s.MaxDepth
+	}
+	return defaultMaxDepth
+}
+
+func (s *Session) leafProbability() float64 {
+	switch {
+	case s.LeafProbability < 0:
+		return 0
+	case s.LeafProbability == 0:
+		return defaultLeafProbability
+	}
+	return s.LeafProbability
+}
@@ -2,8 +2,13 @@ package gopbt
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"testing/quick"
+	"time"
 
 	"github.com/AminMal/gopbt/gen"
 )
@@ -11,26 +16,145 @@ import (
 type Session struct {
 	mapping *typeGenMapping
 
+	// Seed makes checks of this session reproducible, a zero Seed means a new random seed is used for every check.
+	// The -gopbtseed flag and the GOPBT_SEED environment variable take precedence over Seed.
+	Seed int64
+
+	// FailureDatabase is the directory where failing cases are persisted, each property having its own subdirectory named after it.
+	// Persisted cases are checked first by every check, before generating new ones.
+	// An empty FailureDatabase means failing cases are persisted in FailureDatabaseFor(<name of the property>).
+	FailureDatabase string
+
+	// Config is used by Property, and by Check when it is given a nil config
+	Config *quick.Config
+
+	// MaxDiscardRatio is the number of cases which can be discarded by Assume for each check, before giving up with a *GaveUpError.
+	// A zero MaxDiscardRatio means 10.
+	MaxDiscardRatio int
+
+	// Parallel marks the subtests created by Property as parallel, each check drawing from its own source of randomness
+	Parallel bool
+
+	// KeepChannelsOpen leaves generated channels open once filled, by default they are closed so that receiving from them never blocks.
+	// Receiving from an open channel blocks once its elements are consumed, so ranging over it blocks forever, while sending to a closed one panics.
+	// Sending blocks once the buffer of a channel is full, which has room for one more element than the channel is filled with.
+	KeepChannelsOpen bool
+
+	// MaxDepth bounds the nesting of recursive types in themselves, such as linked lists and trees, beyond which nested pointers are nil and nested slices and maps are empty.
+	// A zero MaxDepth means 6.
+	MaxDepth int
+
+	// LeafProbability is the probability for a recursive reference to be nil or empty at the first level of nesting, it grows to 1 at MaxDepth.
+	// A zero LeafProbability means 0.25, and a negative one means that recursive references are never leaves at the first level of nesting.
+	LeafProbability float64
+
+	// MaxSize is the size reached by the last check of a property, sizes grow linearly from 1 across checks, see gen.Sized.
+	// A zero MaxSize means gen.DefaultSize.
+	MaxSize int
+
 	// SupportAdhocGenerators means that the program runtime can create generators for types that don't have any generators available
 	SupportAdhocGenerators bool
+
+	// argGenerators are keyed by the position of the argument of the property, not counting a leading *T
+	argGenerators map[int]argGenerator
+}
+
+// argGenerator is a generator registered for an argument position, which remembers the type it generates to be validated against the argument
+type argGenerator struct {
+	g anyGen
+	t reflect.Type
 }
 
 func (s *Session) getGeneratorFor(t reflect.Type) (anyGen, bool) {
-	g, ok := s.mapping.generatorMapping[t.Name()]
-	return g, ok
+	return s.mapping.generator(t)
+}
+
+func (s *Session) hasGeneratorFor(t reflect.Type) bool {
+	_, ok := s.getGeneratorFor(t)
+	return ok
 }
 
 func NewSessionWithPrimitives() *Session {
-	return &Session{mapping: &typeGenMapping{primitiveGenerators}}
+	// generators are copied, so that generators added to the session are not shared with other sessions
+	generators := make(map[reflect.Type]anyGen, len(primitiveGenerators))
+	for t, g := range primitiveGenerators {
+		generators[t] = g
+	}
+	return &Session{mapping: newTypeGenMapping(generators)}
 }
 
 func NewSession() *Session {
-	return &Session{mapping: &typeGenMapping{make(map[string]anyGen)}}
+	return &Session{mapping: newTypeGenMapping(make(map[reflect.Type]anyGen))}
 }
 
 func SetGen[T any](s *Session, g gen.Generator[T]) {
-	typeName := reflect.TypeOf(*new(T)).Name()
-	s.mapping.setGenerator(typeName, wrap(g))
+	s.mapping.setGenerator(typeOf[T](), wrap(g))
+}
+
+// SetFieldGen sets the generator of the field of struct S, e.g. SetFieldGen[User](s, "Email", emailGen).
+// Field generators take priority over tag and type generators. It panics if S has no such field, or if T is not assignable to it.
+func SetFieldGen[S any, T any](s *Session, field string, g gen.Generator[T]) {
+	structType := typeOf[S]()
+	if structType.Kind() != reflect.Struct {
+		panic(fmt.Errorf("SetFieldGen: %s is not a struct", structType))
+	}
+	f, ok := structType.FieldByName(field)
+	if !ok {
+		panic(fmt.Errorf("SetFieldGen: %s has no field %s", structType, field))
+	}
+	if len(f.Index) != 1 {
+		panic(fmt.Errorf("SetFieldGen: %s.%s is promoted from an embedded struct, set the generator of the field of the embedded struct instead", structType, field))
+	}
+	if !typeOf[T]().AssignableTo(f.Type) {
+		panic(fmt.Errorf("SetFieldGen: cannot use gen.Generator[%s] for field %s.%s of type %s", typeOf[T](), structType, field, f.Type))
+	}
+	s.mapping.setFieldGenerator(structType, field, wrap(g))
+}
+
+// SetTagGen sets the generator of struct fields tagged with `gopbt:"name"`.
+// Tag generators take priority over type generators, but not over field generators.
+func SetTagGen[T any](s *Session, name string, g gen.Generator[T]) {
+	s.mapping.setTagGenerator(name, wrap(g))
+}
+
+// WithArgGen returns a copy of s generating the argument at position with g, not counting a leading *T, e.g.
+// WithArgGen(s, 1, gen.Between(1, 10)).Check(property, nil).
+// Argument generators take priority over type generators. s itself is left unchanged, while other generators are still shared with it.
+func WithArgGen[T any](s *Session, position int, g gen.Generator[T]) *Session {
+	argGenerators := make(map[int]argGenerator, len(s.argGenerators)+1)
+	for p, argGen := range s.argGenerators {
+		argGenerators[p] = argGen
+	}
+	argGenerators[position] = argGenerator{wrap(g), typeOf[T]()}
+
+	scoped := *s
+	scoped.argGenerators = argGenerators
+	return &scoped
+}
+
+// StructOf is like gen.StructOf, but generates the fields missing from fieldGens the way s generates the fields of T,
+// honoring their tags and the generators registered in s. Structs shrink the fields of fieldGens first, then the other fields.
+func StructOf[T any](s *Session, fieldGens map[string]any) gen.Generator[T] {
+	given := gen.StructOf[T](fieldGens)
+	t := typeOf[T]()
+	fieldTypes := make([]reflect.Type, t.NumField())
+	for i := range fieldTypes {
+		fieldTypes[i] = t.Field(i).Type
+	}
+	names := make(map[string]bool, len(fieldGens))
+	for name := range fieldGens {
+		names[name] = true
+	}
+	others := gen.Using[reflect.Value](&simpleAdhocGenerator{s, t, fieldTypes, names}, func(v reflect.Value) T {
+		return v.Interface().(T)
+	})
+	return gen.Map2(given, others, func(g T, o T) T {
+		res := reflect.ValueOf(&o).Elem()
+		for name := range names {
+			res.FieldByName(name).Set(reflect.ValueOf(g).FieldByName(name))
+		}
+		return o
+	})
 }
 
 func functionAndType(f any) (v reflect.Value, t reflect.Type, ok bool) {
@@ -52,20 +176,31 @@ func toInterfaces(values []reflect.Value) []any {
 	return ret
 }
 
-func (s *Session) arbitraryValues(args []reflect.Value, f reflect.Type, config *quick.Config) (err error) {
-	for j := 0; j < len(args); j++ {
+func (s *Session) generatorsFor(types []reflect.Type) (gens []anyGen, err error) {
+	gens = make([]anyGen, len(types))
+	for j := 0; j < len(gens); j++ {
 		// todo, check if it's slice, then we can either lookup, or generate based on the base type
-		correspondingArgType := f.In(j)
-		if gen, ok := s.mapping.generatorMapping[correspondingArgType.Name()]; ok {
-			args[j] = gen.GenerateOne()
+		correspondingArgType := types[j]
+		if argGen, ok := s.argGenerators[j]; ok {
+			if !argGen.t.AssignableTo(correspondingArgType) {
+				err = quick.SetupError(fmt.Sprintf("cannot use gen.Generator[%s] for argument of type %s (argument order: %d)", argGen.t, correspondingArgType, j))
+				return
+			}
+			gens[j] = argGen.g
+		} else if gen, ok := s.getGeneratorFor(correspondingArgType); ok {
+			gens[j] = gen
 		} else if s.SupportAdhocGenerators {
-			g, canGenerateGenerator := s.adhocValueGenerator(correspondingArgType, complexSize)
+			g, canGenerateGenerator, genErr := s.recoveredAdhocValueGenerator(correspondingArgType, j)
+			if genErr != nil {
+				err = genErr
+				return
+			}
 			if !canGenerateGenerator {
 				err = quick.SetupError(fmt.Sprintf("cannot generate gen.Generator[%s] (argument order: %d)", correspondingArgType, j))
 				return
 			}
-			s.mapping.setGenerator(correspondingArgType.Name(), g)
-			args[j] = g.GenerateOne()
+			s.mapping.setGenerator(correspondingArgType, g)
+			gens[j] = g
 		} else {
 			err = quick.SetupError(fmt.Sprintf("no generator found for type %s (argument order: %d)", correspondingArgType, j))
 			return
@@ -75,6 +210,38 @@ func (s *Session) arbitraryValues(args []reflect.Value, f reflect.Type, config *
 	return
 }
 
+// recoveredAdhocValueGenerator is adhocValueGenerator for the argument at argIndex, reporting panics as setup errors
+func (s *Session) recoveredAdhocValueGenerator(t reflect.Type, argIndex int) (g anyGen, ok bool, err error) {
+	defer recoverGenerationPanic(&err, argIndex, t)
+	g, ok = s.adhocValueGenerator(t, gen.DefaultSize)
+	return
+}
+
+// seed resolves the seed of a check, preferring the -gopbtseed flag, then the GOPBT_SEED environment variable, then Session.Seed
+func (s *Session) seed() (int64, error) {
+	if seedFlagSet() {
+		return *seedFlag, nil
+	}
+	if env := os.Getenv(seedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			return 0, quick.SetupError(fmt.Sprintf("invalid %s: %s", seedEnv, err))
+		}
+		return seed, nil
+	}
+	if s.Seed != 0 {
+		return s.Seed, nil
+	}
+	return time.Now().UnixNano(), nil
+}
+
+func (s *Session) config() *quick.Config {
+	if s.Config != nil {
+		return s.Config
+	}
+	return &defaultConfig
+}
+
 func getMaxCount(c *quick.Config) (maxCount int) {
 	maxCount = c.MaxCount
 	if maxCount == 0 {
@@ -88,45 +255,185 @@ func getMaxCount(c *quick.Config) (maxCount int) {
 	return
 }
 
-func validateFunctionType(fType reflect.Type) error {
-	if fType.NumOut() != 1 {
-		return quick.SetupError("function does not return one value")
-	}
-	if fType.Out(0).Kind() != reflect.Bool {
-		return quick.SetupError("function does not return a bool")
-	}
-	return nil
-}
-
+// FunctionReturningBool is a property checked by a Session, declared as either:
+//   - func(...) bool, failing when it returns false
+//   - func(...) error, failing when it returns a non-nil error
+//   - func(...), failing when it panics
+//
+// Any of them may also take a *T as their first argument, failing when the *T is marked as failed.
 type FunctionReturningBool = any
 
+// Check looks for an input for which f fails, and returns a *CheckError describing the (shrunk) input if found.
+// Every input is generated from a seed derived from the seed of the check, which is reported on failure.
+// Failing cases are persisted in the subdirectory of the failure database named after the test calling Check and the type of f,
+// checks of a test sharing the failures of the properties of the same type.
 func (s *Session) Check(f FunctionReturningBool, conf *quick.Config) error {
+	_, _, err := s.check(f, conf, s.failureDatabase(checkName(f)))
+	return err
+}
+
+// check is Check, with failures persisted to failureDatabase.
+// It also returns the number of cases discarded by Assume, and the seed of the check.
+func (s *Session) check(f FunctionReturningBool, conf *quick.Config, failureDatabase string) (discarded int, seed int64, err error) {
 	if conf == nil {
-		conf = &defaultConfig
+		conf = s.config()
 	}
 
 	fVal, fType, ok := functionAndType(f)
 	if !ok {
-		return quick.SetupError("argument is not a function")
+		return 0, 0, quick.SetupError("argument is not a function")
+	}
+
+	p, err := newProperty(fVal, fType)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	generators, err := s.generatorsFor(p.inputTypes())
+	if err != nil {
+		return 0, 0, err
+	}
+	return s.checkProperty(p, generators, conf, failureDatabase)
+}
+
+// checkProperty runs the checks of p, generating its inputs with generators
+func (s *Session) checkProperty(p *property, generators []anyGen, conf *quick.Config, failureDatabase string) (discarded int, seed int64, err error) {
+	seed, err = s.seed()
+	if err != nil {
+		return 0, 0, err
 	}
 
-	if functionValidationErr := validateFunctionType(fType); functionValidationErr != nil {
-		return functionValidationErr
+	entries, paths, err := readFailureDatabase(failureDatabase)
+	if err != nil {
+		return 0, seed, quick.SetupError(fmt.Sprintf("cannot read failure database: %s", err))
+	}
+	for k, entry := range entries {
+		// replayed cases which are now discarded are not counted, as they were not generated by this check
+		checkErr, _, err := s.checkCase(p, generators, entry)
+		if err != nil {
+			return 0, seed, err
+		} else if checkErr != nil {
+			checkErr.Seed = seed
+			checkErr.ReplayedFrom = paths[k]
+			return 0, seed, checkErr
+		}
 	}
 
-	arguments := make([]reflect.Value, fType.NumIn())
+	seeds := rand.New(rand.NewSource(seed))
 	maxCount := getMaxCount(conf)
+	maxDiscarded := maxCount * s.maxDiscardRatio()
 
-	for i := 0; i < maxCount; i++ {
-		err := s.arbitraryValues(arguments, fType, conf)
+	for i := 0; i < maxCount; {
+		entry := failureEntry{seed: seeds.Int63(), size: sizeOf(i, maxCount, s.maxSize())}
+		checkErr, isDiscarded, err := s.checkCase(p, generators, entry)
 		if err != nil {
-			return err
+			return discarded, seed, err
+		} else if checkErr != nil {
+			checkErr.Count = i + 1
+			checkErr.Seed = seed
+			checkErr.Discarded = discarded
+			checkErr.SavedTo, checkErr.SaveErr = writeFailureEntry(failureDatabase, entry)
+			return discarded, seed, checkErr
+		} else if isDiscarded {
+			if discarded++; discarded >= maxDiscarded {
+				return discarded, seed, &GaveUpError{Count: i, Discarded: discarded, Seed: seed}
+			}
+		} else {
+			i++
+		}
+	}
+
+	return discarded, seed, nil
+}
+
+func (s *Session) maxSize() int {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return gen.DefaultSize
+}
+
+// sizeOf returns the size of the i-th of n checks, growing linearly from 1 to maxSize, so that small inputs are checked first
+func sizeOf(i, n, maxSize int) int {
+	if n <= 1 || maxSize <= 1 {
+		return maxSize
+	}
+	return 1 + i*(maxSize-1)/(n-1)
+}
+
+// checkCase generates the inputs described by entry, and returns a *CheckError holding the shrunk inputs if p fails for them,
+// or reports that the inputs were discarded by Assume. Panics of generators are returned as setup errors.
+func (s *Session) checkCase(p *property, generators []anyGen, entry failureEntry) (checkErr *CheckError, discarded bool, err error) {
+	gn := &generation{s: s, src: gen.NewSizedSource(entry.seed, entry.size), generated: newGeneratedValues(true)}
+	defer gn.generated.release()
+	types := p.inputTypes()
+	arguments := make([]gen.Tree[reflect.Value], len(generators))
+	for j, g := range generators {
+		if arguments[j], err = generateArgument(gn, g, j, types[j]); err != nil {
+			return
 		}
+	}
 
-		if !fVal.Call(arguments)[0].Bool() {
-			return &quick.CheckError{Count: i + 1, In: toInterfaces(arguments)}
+	// the last failure is the one of the shrunk inputs, as shrinking only moves to failing inputs
+	var failure *propertyFailure
+	var lastDiscarded bool
+	// inputs discarded by Assume do not fail, so that shrinking never moves to them
+	fails := func(args []reflect.Value) bool {
+		gn.generated.beginEvaluation()
+		f, isDiscarded := p.run(args)
+		lastDiscarded = isDiscarded
+		if f != nil {
+			failure = f
+			gn.generated.keepFailingCalls()
+			return true
 		}
+		return false
+	}
+
+	original := treeValues(arguments)
+	if !fails(original) {
+		return nil, lastDiscarded, nil
+	}
+	// the original inputs are described before shrinking, which evaluates the property with other inputs
+	originalDescriptions := gn.generated.describeAll(original)
+	shrunk, steps, err := shrinkArguments(fails, arguments)
+	if err != nil {
+		return
+	}
+	checkErr = &CheckError{
+		In:       toInterfaces(shrunk),
+		Original: toInterfaces(original),
+		Shrinks:  steps,
+		Message:  failure.message,
+		Panic:    failure.panicValue,
+		Stack:    failure.stack,
+
+		inDescriptions:       gn.generated.describeAll(shrunk),
+		originalDescriptions: originalDescriptions,
 	}
+	return
+}
+
+func generateArgument(gn *generation, g anyGen, argIndex int, argType reflect.Type) (tree gen.Tree[reflect.Value], err error) {
+	defer recoverGenerationPanic(&err, argIndex, argType)
+	return gn.tree(g), nil
+}
+
+// shrinkArguments is shrink, reporting panics of generators computing shrinks as setup errors
+func shrinkArguments(fails func([]reflect.Value) bool, arguments []gen.Tree[reflect.Value]) (shrunk []reflect.Value, steps int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = quick.SetupError(fmt.Sprintf("generator panicked while shrinking: %v", r))
+		}
+	}()
+	shrunk, steps = shrink(fails, arguments)
+	return
+}
 
-	return nil
+// failureDatabase returns the directory where the failing cases of the property named name are persisted
+func (s *Session) failureDatabase(name string) string {
+	if s.FailureDatabase == "" {
+		return FailureDatabaseFor(name)
+	}
+	return filepath.Join(append([]string{s.FailureDatabase}, pathSegments(name)...)...)
 }
@@ -1,7 +1,13 @@
 package gopbt
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
 
 	"github.com/AminMal/gopbt/gen"
 )
@@ -10,6 +16,18 @@ func getGeneratorsLen(s *Session) int {
 	return len(s.mapping.generatorMapping)
 }
 
+func TestMain(m *testing.M) {
+	// most properties of these tests fail on purpose, their failing cases are not persisted in the repository
+	dir, err := os.MkdirTemp("", "gopbt")
+	if err != nil {
+		panic(err)
+	}
+	*failureDatabaseRoot = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestSessionGenerators(t *testing.T) {
 	s := NewSession()
 
@@ -53,3 +71,783 @@ func TestSessionGeneratorAutoAppend(t *testing.T) {
 		t.Errorf("added 3 generators to session, session has %d generators", getGeneratorsLen(s))
 	}
 }
+
+func TestCheckShrinksCounterexample(t *testing.T) {
+	s := NewSessionWithPrimitives()
+
+	noLargeInts := func(i int) bool { return i < 100 }
+
+	err := s.Check(noLargeInts, nil)
+	checkErr, ok := err.(*CheckError)
+	if !ok {
+		t.Fatalf("expected a *CheckError, got %v", err)
+	}
+	if checkErr.In[0] != 100 {
+		t.Errorf("expected the counterexample to shrink to 100, got %v", checkErr.In[0])
+	}
+}
+
+func TestCheckShrinksSlicesAndStructs(t *testing.T) {
+	type pair struct {
+		Left, Right int
+	}
+
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	noPairWithLargeRight := func(pairs []pair) bool {
+		for _, p := range pairs {
+			if p.Right > 10 {
+				return false
+			}
+		}
+		return true
+	}
+
+	err := s.Check(noPairWithLargeRight, nil)
+	checkErr, ok := err.(*CheckError)
+	if !ok {
+		t.Fatalf("expected a *CheckError, got %v", err)
+	}
+	shrunk := checkErr.In[0].([]pair)
+	if len(shrunk) != 1 || shrunk[0] != (pair{0, 11}) {
+		t.Errorf("expected the counterexample to shrink to [{0 11}], got %v", shrunk)
+	}
+	if checkErr.Shrinks == 0 {
+		t.Error("expected at least one shrink step")
+	}
+}
+
+func TestCheckShrinksUsingGeneratorShrinks(t *testing.T) {
+	type even struct {
+		Value int
+	}
+
+	s := NewSession()
+	SetGen(s, gen.Using(gen.Between(0, 1000), func(i int) even { return even{i * 2} }))
+
+	noLargeEvens := func(e even) bool { return e.Value < 100 }
+
+	err := s.Check(noLargeEvens, nil)
+	checkErr, ok := err.(*CheckError)
+	if !ok {
+		t.Fatalf("expected a *CheckError, got %v", err)
+	}
+	if checkErr.In[0] != (even{100}) {
+		t.Errorf("expected the counterexample to shrink to {100} through the generator, got %v", checkErr.In[0])
+	}
+}
+
+func TestCheckIsReproducibleWithSeed(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	s.Seed = 42
+	s.FailureDatabase = t.TempDir()
+
+	noLongSlices := func(ints []int, str string) bool { return len(ints)+len(str) < 60 }
+
+	first := s.Check(noLongSlices, nil)
+	second := NewSessionWithPrimitives()
+	second.SupportAdhocGenerators = true
+	second.Seed = 42
+	second.FailureDatabase = t.TempDir()
+
+	if first == nil {
+		t.Fatal("expected the property to fail")
+	}
+	sameFailure := func(a, b *CheckError) bool {
+		return a.Count == b.Count && formatInputs(a.Original, nil) == formatInputs(b.Original, nil) && formatInputs(a.In, nil) == formatInputs(b.In, nil)
+	}
+	if again, ok := second.Check(noLongSlices, nil).(*CheckError); !ok || !sameFailure(again, first.(*CheckError)) {
+		t.Errorf("expected checks with the same seed to fail identically, got %v and %v", first, again)
+	}
+	if first.(*CheckError).Seed != 42 {
+		t.Errorf("expected the failure to report seed 42, got %d", first.(*CheckError).Seed)
+	}
+}
+
+func TestCheckReplaysFailureDatabase(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.FailureDatabase = t.TempDir()
+
+	noLargeInts := func(i int) bool { return i < 1000 }
+
+	first, ok := s.Check(noLargeInts, nil).(*CheckError)
+	if !ok || first.SavedTo == "" || first.SaveErr != nil {
+		t.Fatalf("expected the failure to be saved to the failure database, got %v", first)
+	}
+
+	replayed, ok := s.Check(noLargeInts, nil).(*CheckError)
+	if !ok || replayed.ReplayedFrom != first.SavedTo {
+		t.Fatalf("expected the failure to be replayed from %s, got %v", first.SavedTo, replayed)
+	}
+	if replayed.Original[0] != first.Original[0] {
+		t.Errorf("expected the replayed case to generate %v again, got %v", first.Original[0], replayed.Original[0])
+	}
+}
+
+func TestPropertiesHaveTheirOwnFailureDatabase(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.FailureDatabase = t.TempDir()
+
+	noLargeInts := func(i int) bool { return i < 1000 }
+	noSmallInts := func(i int64) bool { return i > -1000 }
+
+	first, ok := s.Check(noLargeInts, nil).(*CheckError)
+	if !ok {
+		t.Fatalf("expected a *CheckError, got %v", first)
+	}
+	if filepath.Dir(first.SavedTo) == s.FailureDatabase {
+		t.Errorf("expected the failure to be saved in a subdirectory of %s, got %s", s.FailureDatabase, first.SavedTo)
+	}
+	other, ok := s.Check(noSmallInts, nil).(*CheckError)
+	if !ok || other.ReplayedFrom != "" {
+		t.Errorf("expected the failure of another property not to be replayed, got %v", other)
+	}
+}
+
+func TestCheckPersistsFailuresUnderTheNameOfItsTest(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	checkErr, ok := s.Check(func(i int) bool { return i < 1000 }, nil).(*CheckError)
+	if !ok {
+		t.Fatalf("expected a *CheckError, got %v", checkErr)
+	}
+	expected := FailureDatabaseFor("TestCheckPersistsFailuresUnderTheNameOfItsTest/func(int) bool")
+	if filepath.Dir(checkErr.SavedTo) != expected {
+		t.Errorf("expected the failure to be saved in %s, got %s", expected, checkErr.SavedTo)
+	}
+
+	t.Run("subtest", func(t *testing.T) {
+		replayed, ok := s.Check(func(i int) bool { return i < 1000 }, nil).(*CheckError)
+		if !ok || !strings.HasPrefix(replayed.ReplayedFrom, expected) {
+			t.Errorf("expected the failure to be replayed from %s, got %v", expected, replayed)
+		}
+	})
+}
+
+func TestDecodeFailureEntry(t *testing.T) {
+	entry := failureEntry{seed: -42, size: 7}
+	if decoded, err := decodeFailureEntry(entry.encode()); err != nil || decoded != entry {
+		t.Errorf("expected %+v to be decoded back, got %+v and %v", entry, decoded, err)
+	}
+
+	for _, data := range []string{
+		"gopbt failure v1\nseed: 42\n",
+		"gopbt failure v2\nseed: 42\n",
+		"gopbt failure v2\nseed: 42\nsize: 0\n",
+		"gopbt failure v2\nsize: 7\n",
+	} {
+		if _, err := decodeFailureEntry([]byte(data)); err == nil {
+			t.Errorf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestFailureDatabaseFor(t *testing.T) {
+	expected := filepath.Join(*failureDatabaseRoot, "TestSomething", "sub_test_1", "_")
+	if actual := FailureDatabaseFor("TestSomething/sub test#1/.."); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestCheckSupportsPropertySignatures(t *testing.T) {
+	s := NewSessionWithPrimitives()
+
+	properties := map[string]FunctionReturningBool{
+		"error": func(i int) error {
+			if i >= 100 {
+				return fmt.Errorf("%d is too large", i)
+			}
+			return nil
+		},
+		"panic": func(i int) {
+			if i >= 100 {
+				panic(fmt.Sprintf("%d is too large", i))
+			}
+		},
+		"T": func(t *T, i int) {
+			if i >= 100 {
+				t.Fatalf("%d is too large", i)
+			}
+			t.Log("only reported for failing inputs")
+		},
+	}
+	expectedMessages := map[string]string{
+		"error": "100 is too large",
+		"panic": "panic: 100 is too large",
+		"T":     "100 is too large",
+	}
+
+	for name, property := range properties {
+		checkErr, ok := s.Check(property, nil).(*CheckError)
+		if !ok {
+			t.Errorf("%s: expected a *CheckError", name)
+			continue
+		}
+		if checkErr.In[0] != 100 || checkErr.Message != expectedMessages[name] {
+			t.Errorf("%s: expected 100 to fail with %q, got %v failing with %q", name, expectedMessages[name], checkErr.In[0], checkErr.Message)
+		}
+	}
+}
+
+func TestCheckRejectsInvalidSignatures(t *testing.T) {
+	s := NewSessionWithPrimitives()
+
+	for _, property := range []any{func(int) int { return 0 }, func(int) (bool, error) { return true, nil }, 42} {
+		if _, isSetupErr := s.Check(property, nil).(quick.SetupError); !isSetupErr {
+			t.Errorf("expected a setup error for %T", property)
+		}
+	}
+}
+
+func TestCheckRecoversPropertyPanics(t *testing.T) {
+	s := NewSessionWithPrimitives()
+
+	indexesInRange := func(i int) bool {
+		if i < 0 {
+			i = -i
+		}
+		values := make([]int, 100)
+		return values[i%1000] == 0
+	}
+
+	checkErr, ok := s.Check(indexesInRange, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected the panicking property to fail with a *CheckError")
+	}
+	if checkErr.Panic == nil || !strings.Contains(checkErr.Stack, "TestCheckRecoversPropertyPanics") {
+		t.Errorf("expected the panic and its stack trace to be reported, got %v and %q", checkErr.Panic, checkErr.Stack)
+	}
+	if checkErr.In[0] != 100 && checkErr.In[0] != -100 {
+		t.Errorf("expected the panicking input to shrink to ±100, got %v", checkErr.In[0])
+	}
+}
+
+type counterHolder struct {
+	Name  string
+	Count int
+}
+
+type panickingGenerator struct{}
+
+func (panickingGenerator) GenerateOne() int       { panic("out of ints") }
+func (panickingGenerator) GenerateN(n uint) []int { panic("out of ints") }
+
+func TestCheckReportsGeneratorPanicsAsSetupErrors(t *testing.T) {
+	s := NewSession()
+	s.SupportAdhocGenerators = true
+	SetGen[int](s, panickingGenerator{})
+
+	err := s.Check(func(name string, h []counterHolder) bool { return true }, nil)
+	if _, isSetupErr := err.(quick.SetupError); !isSetupErr || !strings.Contains(err.Error(), "#1") || !strings.Contains(err.Error(), "[].Count") {
+		t.Errorf("expected a setup error locating the counter, got %v", err)
+	}
+
+	s = NewSession()
+	SetGen[int](s, panickingGenerator{})
+	err = s.Check(func(i int) bool { return true }, nil)
+	if _, isSetupErr := err.(quick.SetupError); !isSetupErr || !strings.Contains(err.Error(), "out of ints") {
+		t.Errorf("expected a setup error caused by the generator, got %v", err)
+	}
+}
+
+func TestSessionGeneratorsAreKeyedByType(t *testing.T) {
+	s := NewSession()
+
+	type User struct{ Name string }
+	SetGen(s, gen.Only(User{"first"}))
+	secondUserGen := func() any {
+		type User struct{ Name string }
+		SetGen(s, gen.Only(User{"second"}))
+		return func(u1 User) bool { return u1.Name == "second" }
+	}()
+	SetGen(s, gen.Only([]int{1}))
+	SetGen(s, gen.Only(map[string]int{"one": 1}))
+
+	if getGeneratorsLen(s) != 4 {
+		t.Errorf("expected 4 distinct generators, session has %d generators", getGeneratorsLen(s))
+	}
+
+	property := func(u User, ints []int, m map[string]int) bool {
+		return u.Name == "first" && len(ints) == 1 && m["one"] == 1
+	}
+	if err := s.Check(property, nil); err != nil {
+		t.Errorf("expected each type to resolve to its own generator, got %v", err)
+	}
+	if err := s.Check(secondUserGen, nil); err != nil {
+		t.Errorf("expected same-named types to resolve to their own generator, got %v", err)
+	}
+}
+
+type taggedUser struct {
+	Email string `gopbt:"email"`
+	Name  string `gopbt:"email"`
+	Age   int
+}
+
+func TestSessionNamedGeneratorPriority(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	SetGen(s, gen.Only(-1))
+	SetTagGen(s, "email", gen.Only("someone@example.com"))
+	SetFieldGen[taggedUser](s, "Name", gen.Only("John"))
+	SetFieldGen[taggedUser](s, "Age", gen.Between(0, 120))
+
+	property := func(users []taggedUser, count int, other int) error {
+		for _, u := range users {
+			if u.Email != "someone@example.com" || u.Name != "John" || u.Age < 0 || u.Age >= 120 {
+				return fmt.Errorf("unexpected user %v", u)
+			}
+		}
+		if count < 1 || count >= 10 || other != -1 {
+			return fmt.Errorf("unexpected arguments %d, %d", count, other)
+		}
+		return nil
+	}
+	if err := WithArgGen(s, 1, gen.Between(1, 10)).Check(property, nil); err != nil {
+		t.Error(err)
+	}
+	if _, failed := s.Check(property, &quick.Config{MaxCount: 1}).(*CheckError); !failed {
+		t.Error("expected argument generators to only apply to the session returned by WithArgGen")
+	}
+
+	if _, isSetupErr := WithArgGen(s, 0, gen.Only("not a slice")).Check(property, nil).(quick.SetupError); !isSetupErr {
+		t.Error("expected a setup error for an argument generator of the wrong type")
+	}
+}
+
+type embeddingUser struct {
+	taggedUser
+	Admin bool
+}
+
+func TestSetFieldGenRejectsPromotedFields(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected SetFieldGen to panic for a promoted field")
+		}
+	}()
+	SetFieldGen[embeddingUser](NewSessionWithPrimitives(), "Name", gen.Only("John"))
+}
+
+type agedUser struct {
+	Age int
+}
+
+func TestFieldGeneratorsShrinkWithinTheirDomain(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	SetFieldGen[agedUser](s, "Age", gen.Between(18, 100))
+
+	err := s.Check(func(users []agedUser) bool {
+		return len(users) == 0
+	}, nil)
+	checkErr, ok := err.(*CheckError)
+	if !ok {
+		t.Fatalf("expected a check error, got %v", err)
+	}
+	if users := checkErr.In[0].([]agedUser); len(users) != 1 || users[0].Age != 18 {
+		t.Errorf("expected users to shrink to a single user of the minimal age 18, got %v", users)
+	}
+}
+
+func TestCheckGeneratesFunctions(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	deterministic := func(f func(int, string) bool, i int, str string) bool {
+		return f(i, str) == f(i, str)
+	}
+	if err := s.Check(deterministic, nil); err != nil {
+		t.Errorf("expected generated functions to return the same outputs for the same inputs, got %v", err)
+	}
+
+	alwaysPositive := func(f func(int) int, i int) bool { return f(i) > 0 }
+	checkErr, ok := s.Check(alwaysPositive, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected a *CheckError for a property which does not hold for all functions")
+	}
+	if out := checkErr.In[0].(func(int) int)(12345); out != 0 {
+		t.Errorf("expected the function to shrink to the constant zero function, got %d", out)
+	}
+	if !strings.Contains(checkErr.Error(), "func(int) int{_ → 0}") {
+		t.Errorf("expected the shrunk function to be described as a lookup table, got %s", checkErr.Error())
+	}
+	original, i := checkErr.Original[0].(func(int) int), checkErr.Original[1].(int)
+	if call := fmt.Sprintf("{%d → %d}", i, original(i)); !strings.Contains(checkErr.Error(), call) {
+		t.Errorf("expected the original function to be described by its call %s, got %s", call, checkErr.Error())
+	}
+}
+
+func TestGeneratedFunctionsVaryWithTheirInputs(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	varies := func(f func(int) int) bool {
+		outputs := make(map[int]bool)
+		for i := 0; i < 100; i++ {
+			outputs[f(i)] = true
+		}
+		return len(outputs) > 1
+	}
+	if err := s.Check(varies, nil); err != nil {
+		t.Errorf("expected generated functions to return different outputs for different inputs, got %v", err)
+	}
+}
+
+type shape interface{ Area() float64 }
+
+type square struct{ Side float64 }
+
+func (sq square) Area() float64 { return sq.Side * sq.Side }
+
+type circle struct{ Radius float64 }
+
+func (c circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+func TestCheckGeneratesInterfacesFromImplementations(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	if err := s.Check(func(sh shape) bool { return true }, nil); err == nil {
+		t.Error("expected an error for an interface without implementations")
+	}
+
+	AddImpl[shape, square](s)
+	AddImplGen[shape, circle](s, gen.Using(gen.Between(1.0, 10.0), func(r float64) circle { return circle{r} }))
+
+	seen := make(map[string]bool)
+	err := s.Check(func(shapes []shape) bool {
+		for _, sh := range shapes {
+			seen[fmt.Sprintf("%T", sh)] = true
+			if c, ok := sh.(circle); ok && (c.Radius < 1 || c.Radius >= 10) {
+				return false
+			}
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Errorf("expected circles to be generated by their generator, got %v", err)
+	}
+	if !seen["gopbt.square"] || !seen["gopbt.circle"] {
+		t.Errorf("expected both implementations to be generated, got %v", seen)
+	}
+
+	checkErr, ok := s.Check(func(sh shape) bool { return sh.Area() < 1 }, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected a *CheckError for a property which does not hold for all shapes")
+	}
+	if _, isShape := checkErr.In[0].(shape); !isShape {
+		t.Errorf("expected the counterexample to be a shape, got %#v", checkErr.In[0])
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected AddImpl to panic for a type which does not implement the interface")
+		}
+	}()
+	AddImpl[shape, string](s)
+}
+
+func TestCheckGeneratesPrimitivesForAny(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	seen := make(map[string]bool)
+	err := s.Check(func(values []any) bool {
+		for _, v := range values {
+			seen[fmt.Sprintf("%T", v)] = true
+		}
+		return true
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{"int", "float64", "string", "bool"} {
+		if !seen[typ] {
+			t.Errorf("expected values of type %s to be generated for any, got %v", typ, seen)
+		}
+	}
+}
+
+func TestCheckGeneratesChannels(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	sum := func(c <-chan int) (total int) {
+		for i := range c {
+			total += i % 10
+		}
+		return
+	}
+	checkErr, ok := s.Check(func(c <-chan int) bool { return sum(c) < 20 }, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected a *CheckError for a property which does not hold for all channels")
+	}
+	if got := sum(checkErr.In[0].(<-chan int)); got < 20 {
+		t.Errorf("expected the counterexample channel to be filled with its elements, got a sum of %d", got)
+	}
+	if !strings.Contains(checkErr.Error(), "<-chan int{") || !strings.Contains(checkErr.Error(), "(closed)") {
+		t.Errorf("expected the channel to be described by its elements, got %s", checkErr.Error())
+	}
+
+	s.KeepChannelsOpen = true
+	err := s.Check(func(c chan string, out chan<- bool) bool {
+		filled := len(c)
+		// every channel has room for one more element, so that sending to it does not block
+		c <- "still open"
+		out <- true
+		for i := 0; i <= filled; i++ {
+			<-c
+		}
+		return cap(c) == filled+1 && len(out) == 1
+	}, nil)
+	if err != nil {
+		t.Errorf("expected channels to be buffered and left open, got %v", err)
+	}
+
+	publishedChansMu.Lock()
+	defer publishedChansMu.Unlock()
+	if len(publishedChans) != 0 {
+		t.Errorf("expected check cases to release their channels, %d are still published", len(publishedChans))
+	}
+}
+
+type listNode struct {
+	Value int
+	Next  *listNode
+}
+
+type treeNode struct {
+	Left, Right *treeNode
+	Children    []treeNode
+}
+
+func (n *listNode) length() int {
+	if n == nil {
+		return 0
+	}
+	return 1 + n.Next.length()
+}
+
+func (n *treeNode) depth() int {
+	if n == nil {
+		return 0
+	}
+	d := n.Left.depth()
+	if r := n.Right.depth(); r > d {
+		d = r
+	}
+	for i := range n.Children {
+		if c := n.Children[i].depth(); c > d {
+			d = c
+		}
+	}
+	return 1 + d
+}
+
+func TestCheckBoundsRecursiveTypes(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	s.MaxDepth = 4
+
+	longest := 0
+	err := s.Check(func(l listNode, tree treeNode) bool {
+		if l.length() > longest {
+			longest = l.length()
+		}
+		return l.length() <= 4 && tree.depth() <= 4
+	}, nil)
+	if err != nil {
+		t.Errorf("expected recursive types to be nested at most MaxDepth times, got %v", err)
+	}
+	if longest < 2 {
+		t.Errorf("expected recursive types to be nested, got lists of at most %d nodes", longest)
+	}
+
+	s.LeafProbability = 1
+	err = s.Check(func(l *listNode) bool { return l.length() <= 1 }, nil)
+	if err != nil {
+		t.Errorf("expected recursive references to always be leaves with a leaf probability of 1, got %v", err)
+	}
+
+	s.MaxDepth = 2
+	s.LeafProbability = -1
+	lists, nested := 0, 0
+	err = s.Check(func(l *listNode) bool {
+		if l != nil {
+			lists++
+			if l.length() == 2 {
+				nested++
+			}
+		}
+		return l.length() <= 2
+	}, nil)
+	if err != nil {
+		t.Errorf("expected recursive types to be nested at most MaxDepth times, got %v", err)
+	}
+	if nested < lists*4/5 {
+		t.Errorf("expected recursive references to never be leaves at the first level of nesting with a negative leaf probability, got %d nested lists out of %d", nested, lists)
+	}
+}
+
+type nestedList []nestedList
+
+func (l nestedList) depth() int {
+	depth := 0
+	for _, nested := range l {
+		if d := nested.depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+type nestedMap map[string]nestedMap
+
+func (m nestedMap) depth() int {
+	depth := 0
+	for _, nested := range m {
+		if d := nested.depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+func TestCheckBoundsRecursiveContainers(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+	s.MaxDepth = 4
+
+	deepest := 0
+	err := s.Check(func(l nestedList, m nestedMap) bool {
+		if l.depth() > deepest {
+			deepest = l.depth()
+		}
+		return l.depth() <= 5 && m.depth() <= 5
+	}, nil)
+	if err != nil {
+		t.Errorf("expected self-referential containers to be nested at most MaxDepth times, got %v", err)
+	}
+	if deepest < 2 {
+		t.Errorf("expected self-referential containers to be nested, got a depth of at most %d", deepest)
+	}
+}
+
+func TestCheckGrowsSize(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.MaxSize = 20
+
+	var sizes []int
+	lengths := gen.Sized(func(size int) gen.Generator[int] {
+		sizes = append(sizes, size)
+		return gen.Between(0, size+1)
+	})
+	SetGen(s, lengths)
+	err := s.Check(func(n int) bool {
+		return n <= sizes[len(sizes)-1]
+	}, &quick.Config{MaxCount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sizes[0] != 1 || sizes[len(sizes)-1] != 20 {
+		t.Errorf("expected sizes to grow from 1 to MaxSize, got %v", sizes)
+	}
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i] < sizes[i-1] {
+			t.Errorf("expected sizes to grow, got %v", sizes)
+		}
+	}
+	if n := lengths.GenerateOne(); sizes[len(sizes)-1] != gen.DefaultSize || n > gen.DefaultSize {
+		t.Errorf("expected generators to be given the default size outside of checks, got %d", sizes[len(sizes)-1])
+	}
+
+	s.FailureDatabase = t.TempDir()
+	first, ok := s.Check(func(n int) bool { return n < 10 }, &quick.Config{MaxCount: 100}).(*CheckError)
+	if !ok {
+		t.Fatal("expected the property to fail for large sizes")
+	}
+	s.MaxSize = 1
+	replayed, ok := s.Check(func(n int) bool { return n < 10 }, &quick.Config{MaxCount: 100}).(*CheckError)
+	if !ok || replayed.Original[0] != first.Original[0] {
+		t.Errorf("expected the replayed case to be generated with its original size, got %v", replayed)
+	}
+}
+
+func TestPrimitivesCoverBasicKinds(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	basics := []any{
+		false, 0, int8(0), int16(0), int32(0), int64(0),
+		uint(0), byte(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0), complex64(0), complex128(0), "", 'r',
+	}
+	for _, b := range basics {
+		if !s.hasGeneratorFor(reflect.TypeOf(b)) {
+			t.Errorf("expected a primitive generator for %T", b)
+		}
+	}
+}
+
+func TestCheckDiscardsAssumedCases(t *testing.T) {
+	s := NewSessionWithPrimitives()
+
+	checks := 0
+	err := s.Check(func(a, b int) bool {
+		Assume(b%2 == 0)
+		checks++
+		return (a*b)%2 == 0
+	}, &quick.Config{MaxCount: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks != 50 {
+		t.Errorf("expected discarded cases not to count as checks, got %d checks", checks)
+	}
+
+	checkErr, ok := s.Check(func(i int) bool {
+		Assume(i > 0 || i < -1000)
+		return i > 0
+	}, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected the property to fail")
+	}
+	if checkErr.In[0] != -1001 {
+		t.Errorf("expected shrinking not to move to discarded inputs, got %v", checkErr.In)
+	}
+
+	s.MaxDiscardRatio = 2
+	gaveUp, ok := s.Check(func(i int) bool {
+		Assume(i%10 == 0)
+		return true
+	}, &quick.Config{MaxCount: 100}).(*GaveUpError)
+	if !ok || gaveUp.Discarded != 200 || gaveUp.Count >= 100 {
+		t.Errorf("expected the check to give up after 200 discarded cases, got %v", gaveUp)
+	}
+}
+
+type account struct {
+	Owner   string
+	Balance int
+	Limit   int
+}
+
+func TestStructOfFallsBackToSession(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	SetFieldGen[account](s, "Limit", gen.Between(100, 200))
+	SetGen(s, StructOf[account](s, map[string]any{"Balance": gen.Between(-10, 0)}))
+
+	checkErr, ok := s.Check(func(a account) bool {
+		if a.Balance < -10 || a.Balance >= 0 || a.Limit < 100 || a.Limit >= 200 {
+			t.Fatalf("expected fields to follow their generators, got %+v", a)
+		}
+		return a.Owner == ""
+	}, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected owners to be generated by the session")
+	}
+	if shrunk := checkErr.In[0].(account); len(shrunk.Owner) != 1 || shrunk.Balance != -1 || shrunk.Limit != 100 {
+		t.Errorf("expected every field to shrink, got %+v", shrunk)
+	}
+}
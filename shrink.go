@@ -0,0 +1,412 @@
+package gopbt
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// maxShrinkAttempts bounds the number of times the property is re-evaluated while minimising a counterexample
+const maxShrinkAttempts = 1000
+
+// shrinkValue returns simpler candidates for v, the most aggressive ones first.
+// Candidates never alias the memory of v, so the property under test can freely mutate them.
+func shrinkValue(v reflect.Value) []reflect.Value {
+	t := v.Type()
+	var candidates []reflect.Value
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			candidates = append(candidates, reflect.Zero(t))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		for _, i := range shrinkInt(v.Int()) {
+			c := reflect.New(t).Elem()
+			c.SetInt(i)
+			candidates = append(candidates, c)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		for _, u := range shrinkUint(v.Uint()) {
+			c := reflect.New(t).Elem()
+			c.SetUint(u)
+			candidates = append(candidates, c)
+		}
+	case reflect.Float32, reflect.Float64:
+		for _, f := range shrinkFloat(v.Float()) {
+			c := reflect.New(t).Elem()
+			c.SetFloat(f)
+			candidates = append(candidates, c)
+		}
+	case reflect.Complex64, reflect.Complex128:
+		r, i := real(v.Complex()), imag(v.Complex())
+		for _, sr := range shrinkFloat(r) {
+			c := reflect.New(t).Elem()
+			c.SetComplex(complex(sr, i))
+			candidates = append(candidates, c)
+		}
+		for _, si := range shrinkFloat(i) {
+			c := reflect.New(t).Elem()
+			c.SetComplex(complex(r, si))
+			candidates = append(candidates, c)
+		}
+	case reflect.String:
+		runes := []rune(v.String())
+		for _, rs := range removeChunks(len(runes)) {
+			c := reflect.New(t).Elem()
+			c.SetString(string(pick(runes, rs)))
+			candidates = append(candidates, c)
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		n := v.Len()
+		for _, kept := range removeChunks(n) {
+			c := reflect.MakeSlice(t, len(kept), len(kept))
+			for i, k := range kept {
+				c.Index(i).Set(deepCopy(v.Index(k)))
+			}
+			candidates = append(candidates, c)
+		}
+		for i := 0; i < n; i++ {
+			for _, elem := range shrinkValue(v.Index(i)) {
+				c := deepCopy(v)
+				c.Index(i).Set(elem)
+				candidates = append(candidates, c)
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			for _, elem := range shrinkValue(v.Index(i)) {
+				c := deepCopy(v)
+				c.Index(i).Set(elem)
+				candidates = append(candidates, c)
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() || v.Len() == 0 {
+			break
+		}
+		candidates = append(candidates, reflect.MakeMap(t))
+		keys := v.MapKeys()
+		for _, k := range keys {
+			c := deepCopy(v)
+			c.SetMapIndex(k, reflect.Value{})
+			candidates = append(candidates, c)
+		}
+		for _, k := range keys {
+			for _, elem := range shrinkValue(v.MapIndex(k)) {
+				c := deepCopy(v)
+				c.SetMapIndex(k, elem)
+				candidates = append(candidates, c)
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			break
+		}
+		candidates = append(candidates, reflect.Zero(t))
+		for _, elem := range shrinkValue(v.Elem()) {
+			c := reflect.New(t.Elem())
+			c.Elem().Set(elem)
+			candidates = append(candidates, c)
+		}
+	case reflect.Interface:
+		// values shrink within their implementation
+		if v.IsNil() {
+			break
+		}
+		for _, elem := range shrinkValue(v.Elem()) {
+			c := reflect.New(t).Elem()
+			c.Set(elem)
+			candidates = append(candidates, c)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			// fields only shrink to values satisfying their `gopbt` tag
+			tag := parseTag(t.Field(i).Tag)
+			if tag.err != nil || tag.skip {
+				continue
+			}
+			for _, field := range shrinkValue(v.Field(i)) {
+				if !tag.satisfiedBy(field) {
+					continue
+				}
+				c := deepCopy(v)
+				c.Field(i).Set(field)
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	return candidates
+}
+
+func shrinkInt(i int64) []int64 {
+	if i == 0 {
+		return nil
+	}
+	res := []int64{0}
+	if i < 0 && -i > 0 {
+		res = append(res, -i)
+	}
+	for d := i / 2; d != 0; d /= 2 {
+		if c := i - d; c != 0 {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func shrinkUint(u uint64) []uint64 {
+	if u == 0 {
+		return nil
+	}
+	res := []uint64{0}
+	for d := u / 2; d != 0; d /= 2 {
+		if c := u - d; c != 0 {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func shrinkFloat(f float64) []float64 {
+	if f == 0 {
+		return nil
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return []float64{0}
+	}
+	res := []float64{0}
+	if f < 0 {
+		res = append(res, -f)
+	}
+	if t := math.Trunc(f); t != f && t != 0 {
+		res = append(res, t)
+	}
+	if h := f / 2; math.Abs(h) >= 1 {
+		res = append(res, h)
+	}
+	return res
+}
+
+// removeChunks returns the indices kept after removing chunks of decreasing length from a sequence of length n
+func removeChunks(n int) [][]int {
+	var res [][]int
+	for k := n; k > 0; k /= 2 {
+		for start := 0; start+k <= n; start += k {
+			kept := make([]int, 0, n-k)
+			for i := 0; i < n; i++ {
+				if i < start || i >= start+k {
+					kept = append(kept, i)
+				}
+			}
+			res = append(res, kept)
+		}
+	}
+	return res
+}
+
+func pick[T any](values []T, indices []int) []T {
+	res := make([]T, len(indices))
+	for i, idx := range indices {
+		res[i] = values[idx]
+	}
+	return res
+}
+
+// deepCopy copies v recursively, so that mutations done by the property under test do not leak into reported values
+func deepCopy(v reflect.Value) reflect.Value {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		c := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		c := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		c := reflect.New(t.Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Chan:
+		// generated channels are copied as new channels filled with the same elements, as receiving from a channel consumes it
+		if gc, ok := lookupChan(v); ok {
+			return gc.makeChan()
+		}
+		return v
+	default:
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		return c
+	}
+}
+
+// structTree combines the trees of the fields of a struct of type t, shrinking one field at a time
+func structTree(t reflect.Type, fields []gen.Tree[reflect.Value]) gen.Tree[reflect.Value] {
+	v := reflect.New(t).Elem()
+	for i, f := range fields {
+		v.Field(i).Set(f.Value)
+	}
+	return gen.NewTree(v, func() []gen.Tree[reflect.Value] {
+		var res []gen.Tree[reflect.Value]
+		for i, f := range fields {
+			for _, c := range f.Shrinks() {
+				replaced := make([]gen.Tree[reflect.Value], len(fields))
+				copy(replaced, fields)
+				replaced[i] = c
+				res = append(res, structTree(t, replaced))
+			}
+		}
+		return res
+	})
+}
+
+// sequenceTree combines the trees of the elements of a slice or an array, built from the element values by build.
+// It shrinks by removing elements first, never going below minLength elements, and then by shrinking each element.
+func sequenceTree(elems []gen.Tree[reflect.Value], minLength int, build func([]reflect.Value) reflect.Value) gen.Tree[reflect.Value] {
+	values := make([]reflect.Value, len(elems))
+	for i, e := range elems {
+		values[i] = e.Value
+	}
+	return gen.NewTree(build(values), func() []gen.Tree[reflect.Value] {
+		var res []gen.Tree[reflect.Value]
+		n := len(elems)
+		for k := n - minLength; k > 0; k /= 2 {
+			for start := 0; start+k <= n; start += k {
+				kept := make([]gen.Tree[reflect.Value], 0, n-k)
+				kept = append(kept, elems[:start]...)
+				kept = append(kept, elems[start+k:]...)
+				res = append(res, sequenceTree(kept, minLength, build))
+			}
+		}
+		for i, e := range elems {
+			for _, c := range e.Shrinks() {
+				replaced := make([]gen.Tree[reflect.Value], n)
+				copy(replaced, elems)
+				replaced[i] = c
+				res = append(res, sequenceTree(replaced, minLength, build))
+			}
+		}
+		return res
+	})
+}
+
+// mapTree combines the trees of the keys and values of a map of type t.
+// It shrinks to the empty map first, then by removing one entry at a time, and then by shrinking each value.
+func mapTree(t reflect.Type, keys, values []gen.Tree[reflect.Value]) gen.Tree[reflect.Value] {
+	v := reflect.MakeMapWithSize(t, len(keys))
+	for i := range keys {
+		v.SetMapIndex(keys[i].Value, values[i].Value)
+	}
+	return gen.NewTree(v, func() []gen.Tree[reflect.Value] {
+		n := len(keys)
+		if n == 0 {
+			return nil
+		}
+		res := []gen.Tree[reflect.Value]{mapTree(t, nil, nil)}
+		for i := 0; i < n; i++ {
+			keptKeys := append(append([]gen.Tree[reflect.Value]{}, keys[:i]...), keys[i+1:]...)
+			keptValues := append(append([]gen.Tree[reflect.Value]{}, values[:i]...), values[i+1:]...)
+			res = append(res, mapTree(t, keptKeys, keptValues))
+		}
+		for i, value := range values {
+			for _, c := range value.Shrinks() {
+				replaced := make([]gen.Tree[reflect.Value], n)
+				copy(replaced, values)
+				replaced[i] = c
+				res = append(res, mapTree(t, keys, replaced))
+			}
+		}
+		return res
+	})
+}
+
+// pointerTree points to the values of elem, shrinking to nil first and then by shrinking the value pointed to
+func pointerTree(t reflect.Type, elem gen.Tree[reflect.Value]) gen.Tree[reflect.Value] {
+	v := reflect.New(t.Elem())
+	v.Elem().Set(elem.Value)
+	return gen.NewTree(v, func() []gen.Tree[reflect.Value] {
+		res := []gen.Tree[reflect.Value]{gen.Leaf(reflect.Zero(t))}
+		for _, c := range elem.Shrinks() {
+			res = append(res, pointerTree(t, c))
+		}
+		return res
+	})
+}
+
+// shrink greedily minimises a failing set of arguments, returning the smallest failing arguments found and the number of successful shrink steps
+func shrink(fails func([]reflect.Value) bool, trees []gen.Tree[reflect.Value]) (shrunk []reflect.Value, steps int) {
+	current := make([]gen.Tree[reflect.Value], len(trees))
+	copy(current, trees)
+	attempts := 0
+
+	for improved := true; improved && attempts < maxShrinkAttempts; {
+		improved = false
+		for j := 0; j < len(current) && !improved && attempts < maxShrinkAttempts; j++ {
+			for _, c := range current[j].Shrinks() {
+				attempts++
+				candidate := make([]gen.Tree[reflect.Value], len(current))
+				copy(candidate, current)
+				candidate[j] = c
+				if fails(treeValues(candidate)) {
+					current = candidate
+					steps++
+					improved = true
+					break
+				}
+				if attempts >= maxShrinkAttempts {
+					break
+				}
+			}
+		}
+	}
+
+	return treeValues(current), steps
+}
+
+// treeValues returns copies of the values of trees, so that the property under test cannot mutate the trees themselves
+func treeValues(trees []gen.Tree[reflect.Value]) []reflect.Value {
+	res := make([]reflect.Value, len(trees))
+	for i, t := range trees {
+		res[i] = deepCopy(t.Value)
+	}
+	return res
+}
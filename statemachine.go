@@ -0,0 +1,289 @@
+package gopbt
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing/quick"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// maxCommandAttempts bounds the number of commands generated for a step of a sequence before ending the sequence,
+// when the preconditions of the generated commands do not hold
+const maxCommandAttempts = 100
+
+// Command is an operation taking inputs of type I on a system of type S, checked by CheckStateMachine against a model of type M.
+// Commands without input may use struct{} as I.
+type Command[M any, S any, I any] struct {
+	// Name describes the command in counterexamples
+	Name string
+
+	// Input generates the input of the command in the state of the model, a nil Input means the command takes the zero value of I
+	Input func(model M) gen.Generator[I]
+
+	// Pre tells whether the command can run with input in the state of the model, a nil Pre means it always can.
+	// Commands are only generated when their precondition holds, and shrinking never moves to sequences where it does not.
+	Pre func(model M, input I) bool
+
+	// Run runs the command against the system, and returns its output
+	Run func(system S, input I) any
+
+	// Next returns the state of the model once the command ran, a nil Next means the state is unchanged.
+	// Next must not modify model, which is shared by the sequences tried while shrinking.
+	Next func(model M, input I) M
+
+	// Post checks the output of the command against the state of the model before it ran, a nil Post means any output is fine
+	Post func(model M, input I, output any) error
+}
+
+// AnyCommand is a Command of a StateMachine[M, S], whatever the type of its input
+type AnyCommand[M any, S any] interface {
+	name() string
+	hasRun() bool
+	inputTree(src *gen.Source, model M) gen.Tree[any]
+	pre(model M, input any) bool
+	run(system S, input any) any
+	next(model M, input any) M
+	post(model M, input any, output any) error
+	describe(input any) string
+}
+
+func (c Command[M, S, I]) name() string { return c.Name }
+
+func (c Command[M, S, I]) hasRun() bool { return c.Run != nil }
+
+func (c Command[M, S, I]) inputTree(src *gen.Source, model M) gen.Tree[any] {
+	if c.Input == nil {
+		var zero I
+		return gen.Leaf[any](zero)
+	}
+	return gen.MapTree(gen.GenerateTree(c.Input(model), src), func(input I) any { return input })
+}
+
+func (c Command[M, S, I]) pre(model M, input any) bool {
+	return c.Pre == nil || c.Pre(model, input.(I))
+}
+
+func (c Command[M, S, I]) run(system S, input any) any { return c.Run(system, input.(I)) }
+
+func (c Command[M, S, I]) next(model M, input any) M {
+	if c.Next == nil {
+		return model
+	}
+	return c.Next(model, input.(I))
+}
+
+func (c Command[M, S, I]) post(model M, input any, output any) error {
+	if c.Post == nil {
+		return nil
+	}
+	return c.Post(model, input.(I), output)
+}
+
+func (c Command[M, S, I]) describe(input any) string {
+	if c.Input == nil {
+		return c.Name + "()"
+	}
+	return fmt.Sprintf("%s(%#v)", c.Name, input)
+}
+
+// StateMachine describes a system of type S by a model of type M, and the commands changing them
+type StateMachine[M any, S any] struct {
+	// Name names the state machine in the failure database, under the test calling CheckStateMachine.
+	// Unnamed state machines of a test share their failures, as the properties of the same type checked by Check do.
+	Name string
+
+	// Init returns the initial state of the model
+	Init func() M
+
+	// New creates the system a sequence of commands runs against
+	New func() S
+
+	// Cleanup releases the system once a sequence of commands ran, a nil Cleanup means there is nothing to release
+	Cleanup func(system S)
+
+	Commands []AnyCommand[M, S]
+
+	// MaxCommands bounds the length of sequences of commands.
+	// A zero MaxCommands means sequences are as long as the size of the check, see Session.MaxSize.
+	MaxCommands int
+}
+
+// step is a command of a sequence, with its input
+type step struct {
+	command int
+	input   any
+}
+
+// commandSequence is a sequence of commands generated by a StateMachine, which is described as the calls of the commands, e.g. `Push(1); Pop()`
+type commandSequence[M any, S any] struct {
+	sm    *StateMachine[M, S]
+	steps []step
+}
+
+func (cs commandSequence[M, S]) GoString() string {
+	calls := make([]string, len(cs.steps))
+	for i, st := range cs.steps {
+		calls[i] = cs.sm.describe(st)
+	}
+	return strings.Join(calls, "; ")
+}
+
+func (cs commandSequence[M, S]) String() string { return cs.GoString() }
+
+func (sm *StateMachine[M, S]) describe(st step) string {
+	return sm.Commands[st.command].describe(st.input)
+}
+
+// valid tells whether the preconditions of the steps hold, as they may not anymore once other steps are removed or shrunk
+func (sm *StateMachine[M, S]) valid(steps []step) bool {
+	model := sm.Init()
+	for _, st := range steps {
+		c := sm.Commands[st.command]
+		if !c.pre(model, st.input) {
+			return false
+		}
+		model = c.next(model, st.input)
+	}
+	return true
+}
+
+// run runs the steps against a new system, and returns the error of the first postcondition which does not hold
+func (sm *StateMachine[M, S]) run(cs commandSequence[M, S]) error {
+	system := sm.New()
+	if sm.Cleanup != nil {
+		defer sm.Cleanup(system)
+	}
+	model := sm.Init()
+	for i, st := range cs.steps {
+		c := sm.Commands[st.command]
+		output := c.run(system, st.input)
+		if err := c.post(model, st.input, output); err != nil {
+			return fmt.Errorf("%s (command %d) returned %#v: %w", sm.describe(st), i+1, output, err)
+		}
+		model = c.next(model, st.input)
+	}
+	return nil
+}
+
+// databaseName names the state machine in the failure database, after the test checking it and its Name or type
+func (sm *StateMachine[M, S]) databaseName() string {
+	if sm.Name != "" {
+		return fmt.Sprintf("%s/%s", callingTest(), sm.Name)
+	}
+	return fmt.Sprintf("%s/%T", callingTest(), *sm)
+}
+
+// sequenceGen generates sequences of commands whose preconditions hold
+type sequenceGen[M any, S any] struct {
+	sm *StateMachine[M, S]
+}
+
+func (g sequenceGen[M, S]) GenerateOne() commandSequence[M, S] {
+	return g.GenerateTree(gen.NewSource(rand.Int63())).Value
+}
+
+// GenerateTree shrinks sequences by removing commands, and then by shrinking their inputs
+func (g sequenceGen[M, S]) GenerateTree(src *gen.Source) gen.Tree[commandSequence[M, S]] {
+	sm := g.sm
+	maxCommands := sm.MaxCommands
+	if maxCommands <= 0 {
+		maxCommands = src.Size()
+	}
+	length := draw(src, gen.Between(0, maxCommands+1))
+	model := sm.Init()
+	var steps []gen.Tree[step]
+	for len(steps) < length {
+		st, ok := g.generateStep(src, model)
+		if !ok {
+			break
+		}
+		steps = append(steps, st)
+		model = sm.Commands[st.Value.command].next(model, st.Value.input)
+	}
+	tree := gen.FilterTree(stepsTree(steps), sm.valid)
+	return gen.MapTree(tree, func(steps []step) commandSequence[M, S] { return commandSequence[M, S]{sm, steps} })
+}
+
+// stepsTree combines the trees of steps into a tree of sequences, shrinking by removing runs of consecutive steps, and then by shrinking each step.
+// Runs are removed at every offset, as commands often come in pairs such as `Push(0); Pop()` which may start anywhere in a sequence.
+func stepsTree(steps []gen.Tree[step]) gen.Tree[[]step] {
+	values := make([]step, len(steps))
+	for i, st := range steps {
+		values[i] = st.Value
+	}
+	return gen.NewTree(values, func() []gen.Tree[[]step] {
+		var res []gen.Tree[[]step]
+		n := len(steps)
+		for k := n; k > 0; k /= 2 {
+			for start := 0; start+k <= n; start++ {
+				kept := make([]gen.Tree[step], 0, n-k)
+				kept = append(kept, steps[:start]...)
+				kept = append(kept, steps[start+k:]...)
+				res = append(res, stepsTree(kept))
+			}
+		}
+		for i, st := range steps {
+			for _, c := range st.Shrinks() {
+				replaced := make([]gen.Tree[step], n)
+				copy(replaced, steps)
+				replaced[i] = c
+				res = append(res, stepsTree(replaced))
+			}
+		}
+		return res
+	})
+}
+
+// generateStep generates a command whose precondition holds in the state of the model, if one is found
+func (g sequenceGen[M, S]) generateStep(src *gen.Source, model M) (gen.Tree[step], bool) {
+	commands := g.sm.Commands
+	for attempt := 0; attempt < maxCommandAttempts; attempt++ {
+		i := draw(src, gen.Between(0, len(commands)))
+		c := commands[i]
+		input := c.inputTree(src, model)
+		if c.pre(model, input.Value) {
+			return gen.MapTree(input, func(in any) step { return step{i, in} }), true
+		}
+	}
+	return gen.Tree[step]{}, false
+}
+
+func (g sequenceGen[M, S]) GenerateN(n uint) []commandSequence[M, S] {
+	res := make([]commandSequence[M, S], n)
+	for i := uint(0); i < n; i++ {
+		res[i] = g.GenerateOne()
+	}
+	return res
+}
+
+// CheckStateMachine runs random sequences of the commands of sm against new systems, checking each output against the model.
+// It returns a *CheckError holding the (shrunk) failing sequence if a postcondition does not hold or a command panics,
+// failing sequences shrink by removing commands, and then by shrinking their inputs.
+// Checks are configured and reproduced like the ones of Check, failures being persisted under the Name of sm.
+func CheckStateMachine[M any, S any](s *Session, sm StateMachine[M, S], conf *quick.Config) error {
+	if len(sm.Commands) == 0 {
+		return quick.SetupError("state machine has no command")
+	}
+	if sm.Init == nil || sm.New == nil {
+		return quick.SetupError("state machine has no Init or New function")
+	}
+	for _, c := range sm.Commands {
+		if !c.hasRun() {
+			return quick.SetupError(fmt.Sprintf("command %s has no Run function", c.name()))
+		}
+	}
+	if conf == nil {
+		conf = s.config()
+	}
+	p, err := newProperty(reflect.ValueOf(sm.run), reflect.TypeOf(sm.run))
+	if err != nil {
+		return err
+	}
+
+	generators := []anyGen{wrap[commandSequence[M, S]](sequenceGen[M, S]{&sm})}
+	_, _, err = s.checkProperty(p, generators, conf, s.failureDatabase(sm.databaseName()))
+	return err
+}
@@ -0,0 +1,97 @@
+package gopbt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+type stack struct {
+	items []int
+	// bug stores values from 50 on as their predecessor
+	bug bool
+}
+
+func (s *stack) push(x int) {
+	if s.bug && x >= 50 {
+		x--
+	}
+	s.items = append(s.items, x)
+}
+
+func (s *stack) pop() int {
+	x := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return x
+}
+
+func stackMachine(name string, bug bool) StateMachine[[]int, *stack] {
+	return StateMachine[[]int, *stack]{
+		Name: name,
+		Init: func() []int { return nil },
+		New:  func() *stack { return &stack{bug: bug} },
+		Commands: []AnyCommand[[]int, *stack]{
+			Command[[]int, *stack, int]{
+				Name:  "Push",
+				Input: func([]int) gen.Generator[int] { return gen.Between(0, 100) },
+				Run: func(s *stack, x int) any {
+					s.push(x)
+					return nil
+				},
+				Next: func(m []int, x int) []int { return append(append([]int(nil), m...), x) },
+			},
+			Command[[]int, *stack, struct{}]{
+				Name: "Pop",
+				Pre:  func(m []int, _ struct{}) bool { return len(m) > 0 },
+				Run:  func(s *stack, _ struct{}) any { return s.pop() },
+				Next: func(m []int, _ struct{}) []int { return m[:len(m)-1] },
+				Post: func(m []int, _ struct{}, out any) error {
+					if out != m[len(m)-1] {
+						return fmt.Errorf("expected %d", m[len(m)-1])
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func TestCheckStateMachine(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	if err := CheckStateMachine(s, stackMachine("stack", false), &quick.Config{MaxCount: 200}); err != nil {
+		t.Fatalf("expected the stack to follow its model, got %v", err)
+	}
+
+	checkErr, ok := CheckStateMachine(s, stackMachine("buggy stack", true), &quick.Config{MaxCount: 200}).(*CheckError)
+	if !ok {
+		t.Fatal("expected the buggy stack to fail")
+	}
+	if got := fmt.Sprint(checkErr.In[0]); got != "Push(50); Pop()" {
+		t.Errorf("expected the failing sequence to shrink to Push(50); Pop(), got %s", got)
+	}
+	if !strings.Contains(checkErr.Error(), "Pop() (command 2) returned 49: expected 50") {
+		t.Errorf("expected the failure to describe the failing command, got %s", checkErr.Error())
+	}
+
+	if _, ok := CheckStateMachine(s, StateMachine[[]int, *stack]{}, nil).(quick.SetupError); !ok {
+		t.Error("expected state machines without commands to be setup errors")
+	}
+}
+
+func TestStateMachinesAreKeyedByName(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.FailureDatabase = t.TempDir()
+	if _, ok := CheckStateMachine(s, stackMachine("stack", true), &quick.Config{MaxCount: 200}).(*CheckError); !ok {
+		t.Fatal("expected the buggy stack to fail")
+	}
+	if checkErr, ok := CheckStateMachine(s, stackMachine("other stack", true), &quick.Config{MaxCount: 1}).(*CheckError); ok && checkErr.ReplayedFrom != "" {
+		t.Errorf("expected the failures of another state machine not to be replayed, got one from %s", checkErr.ReplayedFrom)
+	}
+	checkErr, ok := CheckStateMachine(s, stackMachine("stack", true), &quick.Config{MaxCount: 1}).(*CheckError)
+	if !ok || checkErr.ReplayedFrom == "" {
+		t.Errorf("expected the failure of the state machine of the same name to be replayed, got %v", checkErr)
+	}
+}
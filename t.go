@@ -0,0 +1,65 @@
+package gopbt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// T is passed to properties declared as func(*gopbt.T, ...), to report failures the same way *testing.T does.
+// A new T is created for every evaluation of the property.
+type T struct {
+	failed bool
+	logs   []string
+}
+
+// failNow is the panic value used by FailNow to stop the evaluation of a property
+type failNow struct{}
+
+// Fail marks the current evaluation of the property as failed, but continues its execution
+func (t *T) Fail() { t.failed = true }
+
+// FailNow marks the current evaluation of the property as failed, and stops its execution
+func (t *T) FailNow() {
+	t.Fail()
+	panic(failNow{})
+}
+
+// Failed reports whether the current evaluation of the property has failed
+func (t *T) Failed() bool { return t.failed }
+
+// Log records a line, reported along with the counterexample if the property fails
+func (t *T) Log(args ...any) {
+	t.logs = append(t.logs, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+// Logf is like Log, but formats the line according to format
+func (t *T) Logf(format string, args ...any) { t.logs = append(t.logs, fmt.Sprintf(format, args...)) }
+
+// Error is equivalent to Log followed by Fail
+func (t *T) Error(args ...any) {
+	t.Log(args...)
+	t.Fail()
+}
+
+// Errorf is equivalent to Logf followed by Fail
+func (t *T) Errorf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.Fail()
+}
+
+// Fatal is equivalent to Log followed by FailNow
+func (t *T) Fatal(args ...any) {
+	t.Log(args...)
+	t.FailNow()
+}
+
+// Fatalf is equivalent to Logf followed by FailNow
+func (t *T) Fatalf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.FailNow()
+}
+
+// Helper exists for compatibility with *testing.T, T does not report file and line information
+func (t *T) Helper() {}
+
+func (t *T) message() string { return strings.Join(t.logs, "\n") }
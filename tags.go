@@ -0,0 +1,373 @@
+package gopbt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/AminMal/gopbt/gen"
+)
+
+// tagKey is the key of struct tags read by gopbt, e.g. `gopbt:"email"` or `gopbt:"min=0,max=120"`
+const tagKey = "gopbt"
+
+// maxConstraintAttempts bounds the number of values generated for a constrained field before giving up on satisfying all of its constraints
+const maxConstraintAttempts = 100
+
+// structTag is the parsed `gopbt` tag of a struct field, made of comma separated items:
+//   - a bare name selects the generator registered with SetTagGen
+//   - `-` leaves the field to its zero value
+//   - `nonnil` never generates nil pointers, slices or maps
+//   - `min=N` and `max=N` bound numbers, inclusively
+//   - `len=N` or `len=N..M` bound the length of strings (in runes), slices and maps, inclusively
+//   - `oneof=a|b|c` picks among the given values
+//   - `regex=...` generates strings matching the regular expression, and must be the last item as the regular expression may contain commas
+type structTag struct {
+	name   string
+	skip   bool
+	nonNil bool
+
+	min, max string
+
+	hasLen         bool
+	minLen, maxLen int
+
+	oneOf []string
+	regex *regexConstraint
+
+	// err is set when the tag is malformed, and is reported when the field is generated
+	err error
+}
+
+// regexConstraint generates the strings matching a regular expression, and tells whether a string matches it
+type regexConstraint struct {
+	gen     gen.Generator[string]
+	matcher *regexp.Regexp
+}
+
+func newRegexConstraint(pattern string) (*regexConstraint, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	// the whole string must match, not only a part of it
+	return &regexConstraint{gen.StringMatching(pattern), regexp.MustCompile(`^(?:` + pattern + `)$`)}, nil
+}
+
+// parsedTags caches parsed tags, as parsing regular expressions is expensive
+var parsedTags sync.Map // map[reflect.StructTag]*structTag
+
+func parseTag(tag reflect.StructTag) *structTag {
+	if cached, ok := parsedTags.Load(tag); ok {
+		return cached.(*structTag)
+	}
+	parsed := &structTag{}
+	parsed.err = parsed.parse(tag.Get(tagKey))
+	parsedTags.Store(tag, parsed)
+	return parsed
+}
+
+func (st *structTag) parse(tag string) (err error) {
+	for rest := tag; rest != ""; {
+		if strings.HasPrefix(strings.TrimSpace(rest), "regex=") {
+			st.regex, err = newRegexConstraint(strings.TrimPrefix(strings.TrimSpace(rest), "regex="))
+			return
+		}
+		var item string
+		item, rest, _ = strings.Cut(rest, ",")
+		item = strings.TrimSpace(item)
+
+		key, value, hasValue := strings.Cut(item, "=")
+		switch {
+		case item == "":
+		case item == "-":
+			st.skip = true
+		case item == "nonnil":
+			st.nonNil = true
+		case !hasValue:
+			st.name = item
+		case key == "min":
+			st.min = value
+		case key == "max":
+			st.max = value
+		case key == "len":
+			st.hasLen = true
+			minLen, maxLen, isRange := strings.Cut(value, "..")
+			if !isRange {
+				maxLen = minLen
+			}
+			if st.minLen, err = strconv.Atoi(minLen); err != nil {
+				return fmt.Errorf("invalid len %q: %w", value, err)
+			}
+			if st.maxLen, err = strconv.Atoi(maxLen); err != nil {
+				return fmt.Errorf("invalid len %q: %w", value, err)
+			}
+			if st.minLen < 0 || st.minLen > st.maxLen {
+				return fmt.Errorf("invalid len %q", value)
+			}
+		case key == "oneof":
+			st.oneOf = strings.Split(value, "|")
+		default:
+			return fmt.Errorf("unknown gopbt tag item %q", item)
+		}
+	}
+	return
+}
+
+// constrained reports whether the tag restricts the generated values, in which case the field is generated by constrainedTree
+func (st *structTag) constrained() bool {
+	return st.nonNil || st.min != "" || st.max != "" || st.hasLen || st.oneOf != nil || st.regex != nil
+}
+
+// constrainedTree generates a value of type t satisfying the constraints of the tag, which only shrinks to values satisfying them as well
+func (gn *generation) constrainedTree(t reflect.Type, st *structTag, size int) gen.Tree[reflect.Value] {
+	for attempt := 0; attempt < maxConstraintAttempts; attempt++ {
+		tree := gn.candidateTree(t, st, size)
+		if st.satisfiedBy(tree.Value) {
+			return gen.FilterTree(tree, st.satisfiedBy)
+		}
+	}
+	panic(fmt.Errorf("cannot generate a value of type %s satisfying its gopbt tag", t))
+}
+
+// candidateTree generates a value of type t, using the most restrictive constraint of the tag
+func (gn *generation) candidateTree(t reflect.Type, st *structTag, size int) gen.Tree[reflect.Value] {
+	switch {
+	case st.oneOf != nil:
+		options := make([]reflect.Value, len(st.oneOf))
+		for i, o := range st.oneOf {
+			options[i] = parseOption(t, o)
+		}
+		return gen.GenerateTree(gen.OneOf(options...), gn.src)
+	case st.regex != nil:
+		if t.Kind() != reflect.String {
+			panic(fmt.Errorf("regex does not apply to %s", t))
+		}
+		return gen.MapTree(gen.GenerateTree(st.regex.gen, gn.src), func(s string) reflect.Value { return reflect.ValueOf(s).Convert(t) })
+	case st.min != "" || st.max != "":
+		return numberInBounds(t, st.min, st.max, gn.src)
+	case st.hasLen:
+		return gn.valueWithLength(t, st.minLen, st.maxLen, size)
+	}
+
+	if st.nonNil && t.Kind() == reflect.Pointer {
+		return pointerTree(t, gn.mustNestedTree(t.Elem(), size, "*"))
+	}
+	tree, ok := gn.sizedTree(t, size)
+	if !ok {
+		panic(fmt.Errorf("cannot generate value of type `%s`", t))
+	}
+	return tree
+}
+
+func (st *structTag) satisfiedBy(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		if st.nonNil && v.IsNil() {
+			return false
+		}
+	}
+	if st.hasLen {
+		length := 0
+		switch v.Kind() {
+		case reflect.String:
+			length = utf8.RuneCountInString(v.String())
+		case reflect.Slice, reflect.Map, reflect.Array:
+			length = v.Len()
+		}
+		if length < st.minLen || length > st.maxLen {
+			return false
+		}
+	}
+	if (st.min != "" && compareNumber(v, st.min) < 0) || (st.max != "" && compareNumber(v, st.max) > 0) {
+		return false
+	}
+	if st.oneOf != nil {
+		found := false
+		for _, o := range st.oneOf {
+			found = found || parseOption(v.Type(), o).Interface() == v.Interface()
+		}
+		if !found {
+			return false
+		}
+	}
+	return st.regex == nil || st.regex.matcher.MatchString(v.String())
+}
+
+// parseOption parses an option of a `oneof` item as a value of type t
+func parseOption(t reflect.Type, option string) reflect.Value {
+	v := reflect.New(t).Elem()
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(option)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(option)
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(option, 10, t.Bits())
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		u, err = strconv.ParseUint(option, 10, t.Bits())
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(option, t.Bits())
+		v.SetFloat(f)
+	default:
+		err = fmt.Errorf("oneof does not apply to %s", t)
+	}
+	if err != nil {
+		panic(fmt.Errorf("invalid option %q: %w", option, err))
+	}
+	return v
+}
+
+// compareNumber compares the number v with bound, parsed according to the kind of v
+func compareNumber(v reflect.Value, bound string) int {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b := parseOption(v.Type(), bound).Int()
+		return compare(v.Int(), b)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b := parseOption(v.Type(), bound).Uint()
+		return compare(v.Uint(), b)
+	case reflect.Float32, reflect.Float64:
+		b := parseOption(v.Type(), bound).Float()
+		return compare(v.Float(), b)
+	default:
+		panic(fmt.Errorf("min and max do not apply to %s", v.Type()))
+	}
+}
+
+func compare[T gen.Numeric](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// numberInBounds generates a number of type t between the inclusive bounds min and max.
+// Missing bounds default to the range of t, or to half of it for 64 bits types, like the arbitrary generators of package gen.
+func numberInBounds(t reflect.Type, min, max string, src *gen.Source) gen.Tree[reflect.Value] {
+	convert := func(v reflect.Value) reflect.Value { return v.Convert(t) }
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		lo, hi := int64(-1)<<(t.Bits()-1), int64(1)<<(t.Bits()-1)-1
+		if t.Bits() == 64 {
+			lo, hi = math.MinInt64/2+1, math.MaxInt64/2-1
+		}
+		if min != "" {
+			lo = parseOption(t, min).Int()
+		}
+		if max != "" {
+			hi = parseOption(t, max).Int()
+		}
+		if lo > hi {
+			panic(fmt.Errorf("invalid bounds [%d, %d] for %s", lo, hi, t))
+		}
+		return gen.MapTree(gen.GenerateTree(gen.BetweenInclusive(lo, hi), src), func(i int64) reflect.Value { return convert(reflect.ValueOf(i)) })
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lo, hi := uint64(0), uint64(1)<<t.Bits()-1
+		if t.Bits() == 64 {
+			hi = math.MaxInt64 - 1
+		}
+		if min != "" {
+			lo = parseOption(t, min).Uint()
+		}
+		if max != "" {
+			hi = parseOption(t, max).Uint()
+		}
+		if lo > hi {
+			panic(fmt.Errorf("invalid bounds [%d, %d] for %s", lo, hi, t))
+		}
+		return gen.MapTree(gen.GenerateTree(gen.BetweenInclusive(lo, hi), src), func(u uint64) reflect.Value { return convert(reflect.ValueOf(u)) })
+	case reflect.Float32, reflect.Float64:
+		lo, hi := float64(math.MinInt64)/2+1, float64(math.MaxInt64)/2-1
+		if t.Bits() == 32 {
+			lo, hi = float64(math.MinInt32)/2+1, float64(math.MaxInt32)/2-1
+		}
+		if min != "" {
+			lo = parseOption(t, min).Float()
+		}
+		if max != "" {
+			hi = parseOption(t, max).Float()
+		}
+		if lo > hi {
+			panic(fmt.Errorf("invalid bounds [%g, %g] for %s", lo, hi, t))
+		}
+		return gen.MapTree(gen.GenerateTree(gen.Between(lo, hi), src), func(f float64) reflect.Value { return convert(reflect.ValueOf(f)) })
+	default:
+		panic(fmt.Errorf("min and max do not apply to %s", t))
+	}
+}
+
+// valueWithLength generates a string, slice, map or array of type t, with a length between the inclusive bounds minLen and maxLen
+func (gn *generation) valueWithLength(t reflect.Type, minLen, maxLen int, size int) gen.Tree[reflect.Value] {
+	switch t.Kind() {
+	case reflect.String:
+		g := gen.StringOf(gen.ArbitraryRune, uint(minLen), uint(maxLen))
+		return gen.MapTree(gen.GenerateTree(g, gn.src), func(str string) reflect.Value { return reflect.ValueOf(str).Convert(t) })
+	case reflect.Array:
+		// arrays have a fixed length, which must be within the bounds
+		if t.Len() < minLen || t.Len() > maxLen {
+			panic(fmt.Errorf("length of %s is not within [%d, %d]", t, minLen, maxLen))
+		}
+		tree, ok := gn.reflectiveTree(t, size)
+		if !ok {
+			panic(fmt.Errorf("cannot generate value of type `%s`", t))
+		}
+		return tree
+	}
+
+	length := draw(gn.src, gen.Between(minLen, maxLen+1))
+	switch t.Kind() {
+	case reflect.Slice:
+		elems := make([]gen.Tree[reflect.Value], length)
+		for i := range elems {
+			elems[i] = gn.mustNestedTree(t.Elem(), size, "[]")
+		}
+		return sequenceTree(elems, minLen, func(values []reflect.Value) reflect.Value {
+			v := reflect.MakeSlice(t, len(values), len(values))
+			for i, elem := range values {
+				v.Index(i).Set(elem)
+			}
+			return v
+		})
+	case reflect.Map:
+		var keys, values []gen.Tree[reflect.Value]
+		seen := reflect.MakeMap(reflect.MapOf(t.Key(), reflect.TypeOf(true)))
+		// keys may collide, so the map is given a few more attempts to reach its length
+		for attempt := 0; len(keys) < length && attempt < length*maxConstraintAttempts; attempt++ {
+			key := gn.mustNestedTree(t.Key(), size, "[key]")
+			if seen.MapIndex(key.Value).IsValid() {
+				continue
+			}
+			seen.SetMapIndex(key.Value, reflect.ValueOf(true))
+			keys = append(keys, key)
+			values = append(values, gn.mustNestedTree(t.Elem(), size, "[value]"))
+		}
+		return mapTree(t, keys, values)
+	default:
+		panic(fmt.Errorf("len does not apply to %s", t))
+	}
+}
+
+func (gn *generation) mustNestedTree(t reflect.Type, size int, segment string) gen.Tree[reflect.Value] {
+	tree, ok := gn.nestedTree(t, size, segment)
+	if !ok {
+		panic(fmt.Errorf("cannot generate value of type `%s`", t))
+	}
+	return tree
+}
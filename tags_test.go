@@ -0,0 +1,178 @@
+package gopbt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+type constrainedAccount struct {
+	Age      int8              `gopbt:"min=18,max=120"`
+	Balance  float64           `gopbt:"min=0"`
+	Username string            `gopbt:"len=1..20"`
+	Role     string            `gopbt:"oneof=admin|user|guest"`
+	Phone    string            `gopbt:"regex=\\+[0-9]{2,3} [0-9]{3}-[0-9]{4}"`
+	Manager  *string           `gopbt:"nonnil"`
+	Tags     []string          `gopbt:"len=2,nonnil"`
+	Limits   map[string]uint16 `gopbt:"len=1..3"`
+	Internal []int             `gopbt:"-"`
+}
+
+var phonePattern = regexp.MustCompile(`^\+[0-9]{2,3} [0-9]{3}-[0-9]{4}$`)
+
+func validateAccount(a constrainedAccount) error {
+	switch {
+	case a.Age < 18 || a.Age > 120:
+		return fmt.Errorf("age %d out of bounds", a.Age)
+	case a.Balance < 0:
+		return fmt.Errorf("negative balance %g", a.Balance)
+	case utf8.RuneCountInString(a.Username) < 1 || utf8.RuneCountInString(a.Username) > 20:
+		return fmt.Errorf("username %q has an invalid length", a.Username)
+	case a.Role != "admin" && a.Role != "user" && a.Role != "guest":
+		return fmt.Errorf("unexpected role %q", a.Role)
+	case !phonePattern.MatchString(a.Phone):
+		return fmt.Errorf("phone %q does not match", a.Phone)
+	case a.Manager == nil:
+		return fmt.Errorf("nil manager")
+	case len(a.Tags) != 2:
+		return fmt.Errorf("%d tags", len(a.Tags))
+	case len(a.Limits) < 1 || len(a.Limits) > 3:
+		return fmt.Errorf("%d limits", len(a.Limits))
+	case a.Internal != nil:
+		return fmt.Errorf("internal field was generated")
+	}
+	return nil
+}
+
+func TestTagConstraintsAreHonored(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	if err := s.Check(validateAccount, nil); err != nil {
+		t.Errorf("adhoc generator violated tag constraints: %v", err)
+	}
+	if err := s.Check(func(accounts []constrainedAccount) error {
+		for _, a := range accounts {
+			if err := validateAccount(a); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil); err != nil {
+		t.Errorf("reflective generation violated tag constraints: %v", err)
+	}
+}
+
+type accountHolder struct {
+	Accounts []constrainedAccount
+	Primary  *constrainedAccount
+}
+
+func TestTagConstraintsAreHonoredWhileShrinking(t *testing.T) {
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	alwaysFails := func(a constrainedAccount) error {
+		if err := validateAccount(a); err != nil {
+			return fmt.Errorf("invalid shrink: %w", err)
+		}
+		return fmt.Errorf("always fails")
+	}
+
+	checkErr, ok := s.Check(alwaysFails, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected a *CheckError")
+	}
+	if checkErr.Message != "always fails" {
+		t.Errorf("expected shrinking to respect the constraints, got %q", checkErr.Message)
+	}
+	if shrunk := checkErr.In[0].(constrainedAccount); shrunk.Age != 18 || shrunk.Role != "admin" {
+		t.Errorf("expected age and role to shrink to their minimal values, got %d and %s", shrunk.Age, shrunk.Role)
+	}
+
+	// constraints also hold for structs nested in slices and pointers, which are shrunk along with their container
+	var invalid error
+	failsWithAccounts := func(h accountHolder) bool {
+		accounts := h.Accounts
+		if h.Primary != nil {
+			accounts = append(accounts, *h.Primary)
+		}
+		for _, a := range accounts {
+			if err := validateAccount(a); err != nil && invalid == nil {
+				invalid = err
+			}
+		}
+		return len(h.Accounts) == 0 || h.Primary == nil
+	}
+
+	checkErr, ok = s.Check(failsWithAccounts, nil).(*CheckError)
+	if !ok {
+		t.Fatal("expected a *CheckError")
+	}
+	if invalid != nil {
+		t.Errorf("expected nested structs to respect the constraints while shrinking, got %v", invalid)
+	}
+	if shrunk := checkErr.In[0].(accountHolder); len(shrunk.Accounts) != 1 || shrunk.Accounts[0].Age != 18 || shrunk.Primary.Age != 18 {
+		t.Errorf("expected nested accounts to shrink to their minimal age, got %+v", shrunk)
+	}
+}
+
+func TestTagBoundsCoverWholeRanges(t *testing.T) {
+	type bounded struct {
+		NonNegative int64   `gopbt:"min=0,max=9223372036854775807"`
+		Wide        int64   `gopbt:"min=-5000000000000000000,max=5000000000000000000"`
+		Unsigned    uint64  `gopbt:"max=18446744073709551615"`
+		Digits      [3]int8 `gopbt:"len=3"`
+	}
+
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	err := s.Check(func(b bounded) bool {
+		return b.NonNegative >= 0 && b.Wide >= -5000000000000000000 && b.Wide <= 5000000000000000000
+	}, nil)
+	if err != nil {
+		t.Errorf("expected full width bounds and array lengths to be accepted, got %v", err)
+	}
+}
+
+func TestArrayLengthOutOfBoundsIsSetupError(t *testing.T) {
+	type tooShort struct {
+		Digits [3]int `gopbt:"len=4..5"`
+	}
+
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	err := s.Check(func(ts tooShort) bool { return true }, nil)
+	if _, isSetupErr := err.(quick.SetupError); !isSetupErr {
+		t.Errorf("expected a setup error for an array whose length is out of bounds, got %v", err)
+	}
+}
+
+func TestMalformedTagsAreSetupErrors(t *testing.T) {
+	type malformed struct {
+		Name string `gopbt:"min=a"`
+	}
+
+	s := NewSessionWithPrimitives()
+	s.SupportAdhocGenerators = true
+
+	err := s.Check(func(m malformed) bool { return true }, nil)
+	if _, isSetupErr := err.(quick.SetupError); !isSetupErr || !strings.Contains(err.Error(), ".Name") {
+		t.Errorf("expected a setup error locating the malformed tag, got %v", err)
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tag := parseTag(`gopbt:"email, nonnil,len=3..5,regex=[a-z]{1,3},x"`)
+	if tag.err != nil || tag.name != "email" || !tag.nonNil || tag.minLen != 3 || tag.maxLen != 5 || tag.regex == nil {
+		t.Errorf("unexpected parsed tag %+v", tag)
+	}
+	if !tag.regex.matcher.MatchString("ab,x") {
+		t.Error("expected the regular expression to span the rest of the tag")
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 type anyGen interface {
-	gen.Generator[reflect.Value]
+	gen.TreeGenerator[reflect.Value]
 }
 
 func wrap[T any](g gen.Generator[T]) anyGen {
@@ -29,3 +29,7 @@ func (g *generatorWrapper[T]) GenerateN(n uint) []reflect.Value {
 	}
 	return values
 }
+
+func (g *generatorWrapper[T]) GenerateTree(src *gen.Source) gen.Tree[reflect.Value] {
+	return gen.MapTree(gen.GenerateTree(g.g, src), func(t T) reflect.Value { return reflect.ValueOf(t) })
+}